@@ -0,0 +1,205 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Kinds of raw responses that are archived
+const (
+	KindCalendarJSON = "calendar_json"
+	KindReleaseTSV   = "release_tsv"
+	KindEspJSON      = "esp_json"
+	KindScreenerCSV  = "screener_csv"
+	KindTranscript   = "transcript_html"
+	KindSymbolPage   = "symbol_earnings_html"
+)
+
+// Request and response details of a fetched body
+type Exchange struct {
+	Method      string    `json:"method"`
+	URL         string    `json:"url"`
+	RequestBody string    `json:"requestBody,omitempty"`
+	Status      int       `json:"status"`
+	ContentType string    `json:"contentType,omitempty"`
+	FetchedAt   time.Time `json:"fetchedAt"`
+
+	// Fields of the form posted before the request that the response
+	// depends on, e.g. the criteria and tab_id of a screen whose results
+	// are then exported
+	Form map[string][]string `json:"form,omitempty"`
+}
+
+func NewExchange(req *http.Request, requestBody []byte, resp *http.Response, fetchedAt time.Time) *Exchange {
+	return &Exchange{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: string(requestBody),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("content-type"),
+		FetchedAt:   fetchedAt,
+	}
+}
+
+// A line of the archive index. Bodies are stored gzipped under their
+// sha256, so identical responses are only kept once
+type Entry struct {
+	Job     string `json:"job"`
+	JobType string `json:"jobType"`
+	RunID   string `json:"runId"`
+	Kind    string `json:"kind"`
+	Date    string `json:"date"` // query date, 2006-01-02
+	Tab     string `json:"tab,omitempty"`
+
+	Exchange
+
+	Sha256 string `json:"sha256"`
+	Size   int    `json:"size"`
+	Object string `json:"object"` // relative to the archive directory
+}
+
+// A directory of archived responses:
+//
+//	<dir>/index.jsonl             one Entry per archived response
+//	<dir>/objects/ab/ab12....gz   gzipped response bodies
+type Store struct {
+	dir string
+}
+
+func Open(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Stores body and appends its entry to the index
+func (s *Store) Save(e Entry, body []byte) error {
+	sum := sha256.Sum256(body)
+	e.Sha256 = hex.EncodeToString(sum[:])
+	e.Size = len(body)
+	e.Object = filepath.ToSlash(filepath.Join("objects", e.Sha256[:2], e.Sha256+".gz"))
+
+	err := s.writeObject(filepath.Join(s.dir, filepath.FromSlash(e.Object)), body)
+	if err != nil {
+		return fmt.Errorf("error archiving response body: %w", err)
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	index, err := os.OpenFile(filepath.Join(s.dir, "index.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening archive index: %w", err)
+	}
+	defer index.Close()
+
+	_, err = index.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("error writing archive index: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) writeObject(path string, body []byte) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	gz := gzip.NewWriter(tmp)
+	_, err = gz.Write(body)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Reads every entry of the index in the order they were archived
+func (s *Store) Entries() ([]Entry, error) {
+	f, err := os.Open(filepath.Join(s.dir, "index.jsonl"))
+	if os.IsNotExist(err) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []Entry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		e := Entry{}
+		err = json.Unmarshal(line, &e)
+		if err != nil {
+			return nil, fmt.Errorf("corrupt archive index: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, scanner.Err()
+}
+
+// Reads the body of an archived response
+func (s *Store) Load(e Entry) ([]byte, error) {
+	f, err := os.Open(filepath.Join(s.dir, filepath.FromSlash(e.Object)))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	body, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(body)
+	if hex.EncodeToString(sum[:]) != e.Sha256 {
+		return nil, fmt.Errorf("archived object %v does not match its checksum", e.Object)
+	}
+
+	return body, nil
+}
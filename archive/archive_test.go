@@ -0,0 +1,89 @@
+package archive
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	store := Open(t.TempDir())
+	body := []byte(`window.app_data = {"data":[]}`)
+
+	for _, tab := range []string{"earnings", "sales"} {
+		err := store.Save(Entry{
+			Job:  "calendar",
+			Kind: KindCalendarJSON,
+			Date: "2024-01-22",
+			Tab:  tab,
+			Exchange: Exchange{
+				Method: "GET",
+				URL:    "https://www.zacks.com/includes/classes/z2_class_calendarfunctions_data.php",
+				Status: 200,
+			},
+		}, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := store.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].Tab != "sales" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+
+	// Identical bodies are stored once
+	if entries[0].Object != entries[1].Object {
+		t.Fatalf("expected identical bodies to share an object")
+	}
+	objects, err := filepath.Glob(filepath.Join(store.Dir(), "objects", "*", "*.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 {
+		t.Fatalf("expected 1 object, got %v", len(objects))
+	}
+
+	loaded, err := store.Load(entries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(loaded) != string(body) {
+		t.Fatalf("unexpected body: %s", loaded)
+	}
+}
+
+func TestEntriesWithoutIndex(t *testing.T) {
+	entries, err := Open(filepath.Join(t.TempDir(), "missing")).Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected no entries, got %v", len(entries))
+	}
+}
+
+func TestLoadChecksumMismatch(t *testing.T) {
+	store := Open(t.TempDir())
+	for _, body := range []string{`{"data":[]}`, `{"data":[["x"]]}`} {
+		err := store.Save(Entry{Kind: KindEspJSON}, []byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := store.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Point the first entry at the second body
+	entries[0].Object = entries[1].Object
+
+	_, err = store.Load(entries[0])
+	if err == nil {
+		t.Fatal("expected checksum mismatch")
+	}
+}
@@ -15,32 +15,76 @@ type Config struct {
 	Password            string      `yaml:"password"`
 	MaxRetries          int         `yaml:"maxRetries"`
 	DelayBetweenRetries int         `yaml:"delayBetweenRetries"`
+	BaseURLs            BaseURLs    `yaml:"baseUrls"`
 	Jobs                []ScrapeJob `yaml:"jobs"`
 }
 
+// Overrides for the Zacks hosts. Empty values keep the defaults
+type BaseURLs struct {
+	WWW         string `yaml:"www"`         // e.g. https://www.zacks.com
+	ScreenerAPI string `yaml:"screenerApi"` // e.g. https://screener-api.zacks.com
+}
+
 type ScrapeJob struct {
-	JobType    string                   `yaml:"jobType"`
-	OutDir     string                   `yaml:"outDir"`
-	Parameters []map[string]interface{} `yaml:"parameters"`
+	Name         string                   `yaml:"name"` // defaults to the job type
+	JobType      string                   `yaml:"jobType"`
+	OutDir       string                   `yaml:"outDir"`
+	Layout       string                   `yaml:"layout"`       // "flat" (default) or "partitioned"
+	FileTemplate string                   `yaml:"fileTemplate"` // overrides the layout's file naming
+	Provenance   bool                     `yaml:"provenance"`   // add provenance columns to every row
+	ArchiveRaw   bool                     `yaml:"archiveRaw"`   // keep every raw response
+	ArchiveDir   string                   `yaml:"archiveDir"`   // defaults to <outDir>/_raw
+	Validation   map[string]string        `yaml:"validation"`   // action by rule name, or "default"
+	Where        string                   `yaml:"where"`        // keeps only the rows matching an expression
+	Compute      []ComputedColumn         `yaml:"compute"`      // columns added to every row, in order
+	Parameters   []map[string]interface{} `yaml:"parameters"`
+}
+
+// A column computed from the other columns of a row
+type ComputedColumn struct {
+	Name string `yaml:"name"`
+	Expr string `yaml:"expr"`
+}
+
+// Name of the job used in outputs
+func (j *ScrapeJob) DisplayName() string {
+	if j.Name != "" {
+		return j.Name
+	}
+	return j.JobType
 }
 
 func ParseConfigPathFromArgs() (string, error) {
+	return ParseArgFromArgs("config")
+}
+
+// Returns the value of a --name=value command line argument, or "" if it
+// wasn't given
+func ParseArgFromArgs(name string) (string, error) {
 	args := os.Args
 
-	var configPath string
+	var value string
 
 	for _, v := range args {
-		if strings.Contains(v, "--config=") {
-			split := strings.Split(v, "=")
-			if len(split) == 2 {
-				configPath = split[1]
-			} else {
-				return "", fmt.Errorf("invalid arg: %v", v)
-			}
+		if v == "--"+name {
+			return "", fmt.Errorf("invalid arg: %v, expected --%v=value", v, name)
+		}
+		if strings.HasPrefix(v, "--"+name+"=") {
+			// The value may contain = itself, e.g. a screen name
+			value = strings.SplitN(v, "=", 2)[1]
 		}
 	}
 
-	return configPath, nil
+	return value, nil
+}
+
+// Returns the command given before any --flags, e.g. "replay", or "" to
+// run the configured jobs
+func ParseCommandFromArgs() string {
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "--") {
+		return os.Args[1]
+	}
+	return ""
 }
 
 func LoadConfigFile(path string) (*Config, error) {
@@ -2,14 +2,42 @@ package config
 
 import (
 	"log"
+	"os"
 	"testing"
 )
 
 func TestParseConfig(t *testing.T) {
-	path := "../config.yml"
+	path := "../example.yml"
 	config, err := LoadConfigFile(path)
 	if err != nil {
 		t.Fatal(err)
 	}
 	log.Println(*config)
 }
+
+func TestParseArgFromArgs(t *testing.T) {
+	args := os.Args
+	defer func() { os.Args = args }()
+
+	os.Args = []string{"zacks-scraper", "--outDir=out", "--name=P/E=low", "--job_name=other"}
+
+	value, err := ParseArgFromArgs("name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "P/E=low" {
+		t.Fatalf("expected P/E=low, got %q", value)
+	}
+
+	// --outDir doesn't match --dir
+	value, err = ParseArgFromArgs("dir")
+	if err != nil || value != "" {
+		t.Fatalf("expected no value, got %q, %v", value, err)
+	}
+
+	os.Args = []string{"zacks-scraper", "--name"}
+	_, err = ParseArgFromArgs("name")
+	if err == nil {
+		t.Fatal("expected an error for --name without a value")
+	}
+}
@@ -1,13 +1,7 @@
 package earningscalendar
 
 import (
-	"fmt"
-	"log"
-	"os"
-
 	"github.com/iamburbo/zacks-scraper/util"
-	"github.com/xitongsys/parquet-go/parquet"
-	"github.com/xitongsys/parquet-go/writer"
 )
 
 type DividendsDataRow struct {
@@ -33,8 +27,8 @@ func parseDividendsData(rawData *earningsCalendarRawData) []*DividendsDataRow {
 
 func parseDividendsEntry(entry dataEntry) *DividendsDataRow {
 
-	symbol := util.FindStringInBetween(`<span class="hoverquote-symbol">`, `<span class="sr-only">`, entry[0])
-	company := util.FindStringInBetween(`<span title="`, `" >`, entry[1])
+	symbol := util.CellClassText(entry[0], "hoverquote-symbol")
+	company := util.CellAttr(entry[1], "title")
 	marketCap := entry[2]
 	amount := entry[3]
 	yield := entry[4]
@@ -53,25 +47,3 @@ func parseDividendsEntry(entry dataEntry) *DividendsDataRow {
 		PayableDate:  payableDate,
 	}
 }
-
-func writeDividendsData(w *os.File, data []*DividendsDataRow) error {
-	pw, err := writer.NewParquetWriterFromWriter(w, new(DividendsDataRow), 4)
-	if err != nil {
-		return fmt.Errorf("can't create parquet writer: %e", err)
-	}
-
-	pw.RowGroupSize = 128 * 1024 * 1024 //128M
-	pw.CompressionType = parquet.CompressionCodec_SNAPPY
-
-	for _, row := range data {
-		if err = pw.Write(row); err != nil {
-			log.Println("Write error", err)
-		}
-	}
-
-	if err = pw.WriteStop(); err != nil {
-		log.Println("WriteStop error", err)
-	}
-
-	return nil
-}
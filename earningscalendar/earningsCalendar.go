@@ -2,35 +2,43 @@ package earningscalendar
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/iamburbo/zacks-scraper/archive"
 	"github.com/iamburbo/zacks-scraper/config"
+	"github.com/iamburbo/zacks-scraper/output"
+	"github.com/iamburbo/zacks-scraper/zacks"
 )
 
 // Each tab in the calendar window
 var EarningsCalendarTabs = map[string]int{
-	"earnings":  1,
-	"sales":     9,
-	"guidance":  6,
-	"revisions": 3,
-	"dividends": 5,
-	"splits":    4,
-	// "transcripts": 8,
+	"earnings":    1,
+	"sales":       9,
+	"guidance":    6,
+	"revisions":   3,
+	"dividends":   5,
+	"splits":      4,
+	"transcripts": 8,
 }
 
 type earningsCalendarParams struct {
 	start_date time.Time
 	end_date   time.Time
 	tabs       []string
+
+	// Download the text of each transcript listed in the transcripts tab
+	transcript_text bool
+
+	// Also write the rows of every tab as one events dataset per day
+	events bool
 }
 
 // For unmarshaling raw response
@@ -39,23 +47,36 @@ type earningsCalendarRawData struct {
 	Data []dataEntry `json:"data"`
 }
 
-func RunEarningsCalendar(job *config.ScrapeJob, client *http.Client) error {
+func RunEarningsCalendar(job *config.ScrapeJob, client *http.Client, run *output.Run) error {
 
 	params, err := parseJobParameters(job.Parameters)
 	if err != nil {
 		return err
 	}
 
-	currentDate := time.Now()
-	job.OutDir = filepath.Join(job.OutDir, currentDate.Format("200601021504"))
-	os.MkdirAll(job.OutDir, 0755)
+	// The tabs and events have different columns, so expressions are only
+	// applied to the tables that have the columns they read
+	mixed := len(params.tabs) > 1 || params.events || params.transcript_text
 
 	// Collect and write data
 	temp := params.start_date
 	for params.end_date.Sub(temp) >= 0 {
+		events := []*EventRow{}
+		var eventsScrapedAt time.Time
+
 		for _, tab := range params.tabs {
 			// Fetch data
-			body, err := getEarningsCalendarData(temp, tab, client)
+			body, exchange, err := run.Fetch(job, archive.Entry{
+				Kind: archive.KindCalendarJSON,
+				Date: temp.Format("2006-01-02"),
+				Tab:  tab,
+			}, func() ([]byte, *archive.Exchange, error) {
+				return getEarningsCalendarData(temp, tab, client)
+			})
+			if errors.Is(err, output.ErrNotArchived) {
+				log.Printf("skipping earnings calendar: %v", err)
+				continue
+			}
 			if err != nil {
 				return err
 			}
@@ -63,56 +84,76 @@ func RunEarningsCalendar(job *config.ScrapeJob, client *http.Client) error {
 			// Parse and save data
 			data, err := parseEarningsCalendarBody(body)
 			if err != nil {
-				log.Printf("error parsing earnings data: %e", err)
+				return fmt.Errorf("error parsing %v data: %w", tab, err)
 			}
 
-			// Create output file
-			fileName := temp.Format("20060102150405") + "_" + tab + ".parquet"
-			w, err := os.Create(filepath.Join(job.OutDir, fileName))
+			table, err := parseTabData(tab, data)
+			var drift *SchemaDriftError
+			if errors.As(err, &drift) {
+				name := fmt.Sprintf("%v_%v_%v.js", job.DisplayName(), temp.Format("2006-01-02"), tab)
+				drift.Payload, err = run.SaveDiagnostic(job, name, body)
+				if err != nil {
+					log.Printf("error saving %v payload: %v", tab, err)
+				}
+				return drift
+			}
 			if err != nil {
-				return fmt.Errorf("failed to create local file: %e", err)
+				return fmt.Errorf("error parsing %v data: %w", tab, err)
 			}
 
-			switch tab {
-			case "earnings":
-				rows := parseEarningsData(data)
-				err = writeEarningsData(w, rows)
-				if err != nil {
-					log.Printf("error writing earnings data: %e", err)
-				}
-			case "sales":
-				rows := parseSalesData(data)
-				err = writeSalesData(w, rows)
-				if err != nil {
-					log.Printf("error writing earnings data: %e", err)
-				}
-			case "guidance":
-				rows := parseGuidanceData(data)
-				err = writeGuidanceData(w, rows)
-				if err != nil {
-					log.Printf("error writing earnings data: %e", err)
-				}
-			case "revisions":
-				rows := parseRevisionsData(data)
-				err = writeRevisionsData(w, rows)
-				if err != nil {
-					log.Printf("error writing earnings data: %e", err)
-				}
-			case "dividends":
-				rows := parseDividendsData(data)
-				err = writeDividendsData(w, rows)
+			err = run.WriteTable(job, output.Partition{
+				Tab:          tab,
+				Date:         temp,
+				Ext:          "parquet",
+				FlatTemplate: "{run_time}/{timestamp}_{tab}.{ext}",
+				Endpoint:     zacks.CalendarURL(),
+				ScrapedAt:    exchange.FetchedAt,
+				Rules:        tabRules(tab, temp),
+
+				SkipMissingColumns: mixed,
+			}, table)
+			if err != nil {
+				return err
+			}
+
+			if tab == "transcripts" && params.transcript_text {
+				err = writeTranscriptTexts(job, client, run, temp, parseTranscriptsData(data))
 				if err != nil {
-					log.Printf("error writing earnings data: %e", err)
+					return err
 				}
-			case "splits":
-				rows := parseSplitsData(data)
-				err = writeSplitsData(w, rows)
-				if err != nil {
-					log.Printf("error writing earnings data: %e", err)
+			}
+
+			if params.events {
+				events = append(events, tabEvents(tab, temp, data)...)
+				if eventsScrapedAt.IsZero() {
+					eventsScrapedAt = exchange.FetchedAt
 				}
 			}
+		}
 
-			w.Close()
+		if params.events && !eventsScrapedAt.IsZero() {
+			table, err := output.NewTable("earnings_calendar.events", events)
+			if err != nil {
+				return err
+			}
+
+			err = run.WriteTable(job, output.Partition{
+				Dataset:      "earnings_events",
+				Date:         temp,
+				Ext:          "parquet",
+				FlatTemplate: "{run_time}/{timestamp}_events.{ext}",
+				Endpoint:     zacks.CalendarURL(),
+				ScrapedAt:    eventsScrapedAt,
+				Rules: []output.Rule{
+					output.SymbolFormat("symbol"),
+					output.UniqueKey("symbol", "date", "event_type"),
+				},
+
+				SkipMissingColumns: true,
+			}, table)
+			if err != nil {
+				return err
+			}
 		}
 
 		temp = temp.Add(24 * time.Hour)
@@ -121,11 +162,64 @@ func RunEarningsCalendar(job *config.ScrapeJob, client *http.Client) error {
 	return nil
 }
 
+// Parses the rows of a tab into a table for writing. Payloads that don't
+// match the tab's schema return a *SchemaDriftError
+func parseTabData(tab string, data *earningsCalendarRawData) (*output.Table, error) {
+	err := validateTabData(tab, data)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := "earnings_calendar." + tab
+	switch tab {
+	case "earnings":
+		return output.NewTable(schema, parseEarningsData(data))
+	case "sales":
+		return output.NewTable(schema, parseSalesData(data))
+	case "guidance":
+		return output.NewTable(schema, parseGuidanceData(data))
+	case "revisions":
+		return output.NewTable(schema, parseRevisionsData(data))
+	case "dividends":
+		return output.NewTable(schema, parseDividendsData(data))
+	case "splits":
+		return output.NewTable(schema, parseSplitsData(data))
+	case "transcripts":
+		return output.NewTable(schema, parseTranscriptsData(data))
+	default:
+		return nil, fmt.Errorf("unknown tab: %v", tab)
+	}
+}
+
+// Data quality checks for the rows of a tab queried for a day
+func tabRules(tab string, date time.Time) []output.Rule {
+	rules := []output.Rule{
+		output.SymbolFormat("symbol"),
+	}
+
+	switch tab {
+	case "earnings", "sales":
+		rules = append(rules, output.UniqueSymbol("symbol"), output.NumericEstimate("estimate"))
+	case "guidance", "revisions":
+		// A symbol has a row for each period it gives guidance for or
+		// has its estimates revised for
+		rules = append(rules, output.UniqueKey("symbol", "period", "periodEnd"))
+	case "dividends":
+		rules = append(rules, output.UniqueSymbol("symbol"), output.DateInRange("exDivDate", "1/2/2006", date, date))
+	default:
+		rules = append(rules, output.UniqueSymbol("symbol"))
+	}
+
+	return rules
+}
+
 // Parses arguments from config yaml
 func parseJobParameters(parameters []map[string]interface{}) (*earningsCalendarParams, error) {
 	var start_date time.Time
 	var end_date time.Time
 	var tabs []string
+	var transcript_text bool
+	var events bool
 
 	for _, p := range parameters {
 		if t, ok := p["start_date_offset"]; ok {
@@ -176,6 +270,20 @@ func parseJobParameters(parameters []map[string]interface{}) (*earningsCalendarP
 			}
 		}
 
+		if t, ok := p["transcript_text"]; ok {
+			transcript_text, ok = t.(bool)
+			if !ok {
+				return nil, fmt.Errorf("transcript_text must be true or false, got %v", t)
+			}
+		}
+
+		if t, ok := p["events"]; ok {
+			events, ok = t.(bool)
+			if !ok {
+				return nil, fmt.Errorf("events must be true or false, got %v", t)
+			}
+		}
+
 		if t, ok := p["tabs"]; ok {
 			tInt := t.([]interface{})
 			for _, v := range tInt {
@@ -185,7 +293,7 @@ func parseJobParameters(parameters []map[string]interface{}) (*earningsCalendarP
 	}
 
 	if len(tabs) == 0 {
-		// NOTE: Excluded transcripts
+		// Transcripts are only fetched when asked for
 		tabs = []string{"earnings", "sales", "guidance", "revisions", "dividends", "splits"}
 	}
 
@@ -193,14 +301,17 @@ func parseJobParameters(parameters []map[string]interface{}) (*earningsCalendarP
 		start_date: start_date,
 		end_date:   end_date,
 		tabs:       tabs,
+
+		transcript_text: transcript_text,
+		events:          events,
 	}, nil
 }
 
 // Fetches raw earnings calendar data from Zacks
-func getEarningsCalendarData(timestamp time.Time, tab string, client *http.Client) ([]byte, error) {
-	u, err := url.Parse("https://www.zacks.com/includes/classes/z2_class_calendarfunctions_data.php")
+func getEarningsCalendarData(timestamp time.Time, tab string, client *http.Client) ([]byte, *archive.Exchange, error) {
+	u, err := url.Parse(zacks.CalendarURL())
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	q := u.Query()
@@ -213,15 +324,16 @@ func getEarningsCalendarData(timestamp time.Time, tab string, client *http.Clien
 
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	req.Header.Set("user-agent", `Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/106.0.0.0 Safari/537.36`)
 	req.Header.Set("accept", "text/plain, */*; q=0.01")
 
+	fetchedAt := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
@@ -229,12 +341,12 @@ func getEarningsCalendarData(timestamp time.Time, tab string, client *http.Clien
 	case 200:
 		bodyBytes, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
-		return bodyBytes, nil
+		return bodyBytes, archive.NewExchange(req, nil, resp, fetchedAt), nil
 	default:
-		return nil, fmt.Errorf("status code %d", resp.StatusCode)
+		return nil, nil, fmt.Errorf("status code %d", resp.StatusCode)
 	}
 }
 
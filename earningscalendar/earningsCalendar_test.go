@@ -0,0 +1,452 @@
+package earningscalendar
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iamburbo/zacks-scraper/config"
+	"github.com/iamburbo/zacks-scraper/output"
+	"github.com/iamburbo/zacks-scraper/zacks"
+	"github.com/iamburbo/zacks-scraper/zackstest"
+)
+
+func TestRunEarningsCalendar(t *testing.T) {
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &config.ScrapeJob{
+		JobType: "earnings_calendar",
+		OutDir:  t.TempDir(),
+		Layout:  output.LayoutPartitioned,
+		Parameters: []map[string]interface{}{
+			{"start_date": "2024-01-22"},
+			{"end_date": "2024-01-22"},
+			{"tabs": []interface{}{"earnings", "sales"}},
+		},
+	}
+
+	err = RunEarningsCalendar(job, client, output.NewRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tab := range []string{"earnings", "sales"} {
+		files, err := filepath.Glob(filepath.Join(job.OutDir, "dataset=earnings_calendar", "tab="+tab, "date=2024-01-22", "*.parquet"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 {
+			t.Fatalf("expected 1 %v file, got %v", tab, len(files))
+		}
+
+		rows, err := zackstest.ReadParquet(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) == 0 {
+			t.Fatalf("expected %v rows", tab)
+		}
+		if rows[0]["symbol"] != "AAPL" || rows[0]["company"] != "Apple Inc." {
+			t.Fatalf("unexpected %v row: %+v", tab, rows[0])
+		}
+	}
+
+	if n := len(server.RequestsTo("/includes/classes/z2_class_calendarfunctions_data.php")); n != 2 {
+		t.Fatalf("expected 2 calendar requests, got %v", n)
+	}
+}
+
+// A where filter reading an earnings column only applies to the earnings
+// tab, the other tabs and the events are written unfiltered
+func TestRunEarningsCalendarWhereMultipleTabs(t *testing.T) {
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tabs := []interface{}{"earnings", "guidance", "revisions", "dividends", "splits"}
+	job := &config.ScrapeJob{
+		JobType: "earnings_calendar",
+		OutDir:  t.TempDir(),
+		Layout:  output.LayoutPartitioned,
+		Where:   "percentSurp > 5",
+		Parameters: []map[string]interface{}{
+			{"start_date": "2024-01-22"},
+			{"end_date": "2024-01-22"},
+			{"tabs": tabs},
+			{"events": true},
+		},
+	}
+
+	err = RunEarningsCalendar(job, client, output.NewRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(dataset, tab string) []map[string]interface{} {
+		dir := filepath.Join(job.OutDir, "dataset="+dataset)
+		if tab != "" {
+			dir = filepath.Join(dir, "tab="+tab)
+		}
+		files, err := filepath.Glob(filepath.Join(dir, "date=2024-01-22", "*.parquet"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 {
+			t.Fatalf("expected 1 %v file, got %v", dir, len(files))
+		}
+		rows, err := zackstest.ReadParquet(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	earnings := read("earnings_calendar", "earnings")
+	if len(earnings) != 1 || earnings[0]["symbol"] != "MSFT" {
+		t.Fatalf("expected only MSFT to beat by more than 5%%, got %+v", earnings)
+	}
+	for _, tab := range tabs[1:] {
+		if rows := read("earnings_calendar", tab.(string)); len(rows) == 0 {
+			t.Fatalf("expected %v rows", tab)
+		}
+	}
+	if rows := read("earnings_events", ""); len(rows) == 0 {
+		t.Fatal("expected events")
+	}
+}
+
+// The dividends fixture has AAPL going ex-dividend on 2/9/2024 and KO on
+// 2/14/2024, so querying 2/14 has one row outside the queried day
+func TestRunEarningsCalendarExDivDateInRange(t *testing.T) {
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(action string) (*config.ScrapeJob, *output.Run) {
+		job := &config.ScrapeJob{
+			JobType:    "earnings_calendar",
+			OutDir:     t.TempDir(),
+			Layout:     output.LayoutPartitioned,
+			Validation: map[string]string{"date_in_range": action},
+			Parameters: []map[string]interface{}{
+				{"start_date": "2024-02-14"},
+				{"end_date": "2024-02-14"},
+				{"tabs": []interface{}{"dividends"}},
+			},
+		}
+		r := output.NewRun()
+		err := RunEarningsCalendar(job, client, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = r.WriteManifests()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return job, r
+	}
+
+	read := func(job *config.ScrapeJob) []map[string]interface{} {
+		files, err := filepath.Glob(filepath.Join(job.OutDir, "dataset=earnings_calendar", "tab=dividends", "date=2024-02-14", "*.parquet"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 {
+			t.Fatalf("expected 1 dividends file, got %v", len(files))
+		}
+		rows, err := zackstest.ReadParquet(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	// Warned rows are kept and counted in the manifest
+	job, r := run(output.ActionWarn)
+	if rows := read(job); len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %+v", rows)
+	}
+	data, err := os.ReadFile(filepath.Join(job.OutDir, "_manifests", r.ID+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := output.Manifest{}
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Files) != 1 || manifest.Files[0].Validation["date_in_range"] != 1 {
+		t.Fatalf("expected 1 date_in_range failure, got %+v", manifest.Files)
+	}
+
+	// Dropped rows are left out
+	job, _ = run(output.ActionDrop)
+	rows := read(job)
+	if len(rows) != 1 || rows[0]["symbol"] != "KO" {
+		t.Fatalf("expected only the KO row, got %+v", rows)
+	}
+}
+
+func TestRunEarningsCalendarTranscripts(t *testing.T) {
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &config.ScrapeJob{
+		JobType: "earnings_calendar",
+		OutDir:  t.TempDir(),
+		Layout:  output.LayoutPartitioned,
+		Parameters: []map[string]interface{}{
+			{"start_date": "2024-01-22"},
+			{"end_date": "2024-01-22"},
+			{"tabs": []interface{}{"transcripts"}},
+			{"transcript_text": true},
+		},
+	}
+
+	err = RunEarningsCalendar(job, client, output.NewRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(dataset, tab string) []map[string]interface{} {
+		files, err := filepath.Glob(filepath.Join(job.OutDir, "dataset="+dataset, "tab="+tab, "date=2024-01-22", "*.parquet"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 {
+			t.Fatalf("expected 1 %v file, got %v", tab, len(files))
+		}
+		rows, err := zackstest.ReadParquet(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	transcripts := read("earnings_calendar", "transcripts")
+	if len(transcripts) != 2 {
+		t.Fatalf("expected 2 transcripts rows, got %v", len(transcripts))
+	}
+	if transcripts[0]["transcriptUrl"] != zacks.WWW+"/stock/research/AAPL/earnings-call-transcripts" {
+		t.Fatalf("unexpected transcript link: %+v", transcripts[0])
+	}
+	if transcripts[1]["transcriptUrl"] != "" {
+		t.Fatalf("expected no link for NKE: %+v", transcripts[1])
+	}
+
+	// Only AAPL has a transcript to download
+	texts := read("earnings_call_transcripts", "transcript_text")
+	if len(texts) != 1 || texts[0]["symbol"] != "AAPL" {
+		t.Fatalf("unexpected transcript texts: %+v", texts)
+	}
+	text := texts[0]["text"].(string)
+	if !strings.HasPrefix(text, "Operator: Good day") || !strings.Contains(text, "\nTim Cook: Thank you.") {
+		t.Fatalf("unexpected transcript text: %q", text)
+	}
+	if strings.Contains(text, "Markets") {
+		t.Fatalf("expected only the article text: %q", text)
+	}
+
+	if n := len(server.RequestsTo("/stock/research/AAPL/earnings-call-transcripts")); n != 1 {
+		t.Fatalf("expected 1 transcript request, got %v", n)
+	}
+}
+
+func TestRunEarningsCalendarEvents(t *testing.T) {
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &config.ScrapeJob{
+		JobType: "earnings_calendar",
+		OutDir:  t.TempDir(),
+		Layout:  output.LayoutPartitioned,
+		Parameters: []map[string]interface{}{
+			{"start_date": "2024-01-22"},
+			{"end_date": "2024-01-22"},
+			{"events": true},
+		},
+	}
+
+	err = RunEarningsCalendar(job, client, output.NewRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Written alongside the tabs
+	tabs, err := filepath.Glob(filepath.Join(job.OutDir, "dataset=earnings_calendar", "tab=*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tabs) != 6 {
+		t.Fatalf("expected 6 tabs, got %v", tabs)
+	}
+
+	files, err := filepath.Glob(filepath.Join(job.OutDir, "dataset=earnings_events", "date=2024-01-22", "*.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 events file, got %v", len(files))
+	}
+
+	rows, err := zackstest.ReadParquet(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byType := map[string][]map[string]interface{}{}
+	for _, r := range rows {
+		byType[r["event_type"].(string)] = append(byType[r["event_type"].(string)], r)
+	}
+	for _, eventType := range []string{"earnings", "sales", "guidance", "revision", "dividend", "split"} {
+		if len(byType[eventType]) == 0 {
+			t.Fatalf("expected %v events, got %+v", eventType, rows)
+		}
+	}
+
+	dividend := byType["dividend"][0]
+	if dividend["symbol"] != "AAPL" || dividend["details_dividend_amount"] != 0.24 || dividend["details_estimate"] != nil {
+		t.Fatalf("unexpected dividend event: %+v", dividend)
+	}
+	guidance := byType["guidance"][1]
+	if guidance["symbol"] != "NKE" || guidance["details_guidance_low"] != 3.1 || guidance["details_guidance_high"] != 3.35 {
+		t.Fatalf("unexpected guidance event: %+v", guidance)
+	}
+	split := byType["split"][0]
+	if split["details_split_factor"] != "10-1" || split["market_cap"] != 2955390.0 {
+		t.Fatalf("unexpected split event: %+v", split)
+	}
+}
+
+// The guidance fixture has NKE guiding for the fiscal year and the quarter.
+// Both rows are valid on the tab, and merge into one event
+func TestRunEarningsCalendarEventsPeriods(t *testing.T) {
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &config.ScrapeJob{
+		JobType:    "earnings_calendar",
+		OutDir:     t.TempDir(),
+		Layout:     output.LayoutPartitioned,
+		Validation: map[string]string{"default": output.ActionFail},
+		Parameters: []map[string]interface{}{
+			{"start_date": "2024-01-22"},
+			{"end_date": "2024-01-22"},
+			{"tabs": []interface{}{"guidance"}},
+			{"events": true},
+		},
+	}
+
+	err = RunEarningsCalendar(job, client, output.NewRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(dir string) []map[string]interface{} {
+		files, err := filepath.Glob(filepath.Join(job.OutDir, dir, "date=2024-01-22", "*.parquet"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 {
+			t.Fatalf("expected 1 %v file, got %v", dir, len(files))
+		}
+		rows, err := zackstest.ReadParquet(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	if rows := read(filepath.Join("dataset=earnings_calendar", "tab=guidance")); len(rows) != 3 {
+		t.Fatalf("expected a guidance row per period, got %+v", rows)
+	}
+
+	rows := read("dataset=earnings_events")
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 events, got %+v", rows)
+	}
+
+	guidance := rows[1]
+	if guidance["symbol"] != "NKE" || guidance["details_period"] != "FY" || guidance["details_guidance_low"] != 3.1 {
+		t.Fatalf("expected the details of the first period, got %+v", guidance)
+	}
+	if guidance["details_periods"] != "FY 5/2024; Q 2/2024" {
+		t.Fatalf("unexpected periods: %v", guidance["details_periods"])
+	}
+}
+
+func TestRunEarningsCalendarSchemaDrift(t *testing.T) {
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	// Zacks dropped the market cap column
+	server.SetFixture("calendar_splits.js", []byte(`window.app_data = {"data": [["<a href=\"/stock/quote/NVDA\"><span class=\"hoverquote-symbol\">NVDA</span></a>", "<span title=\"NVIDIA Corporation\" >NVIDIA Corporation</span>", "1,208.88", "10-1"]]}`))
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &config.ScrapeJob{
+		JobType: "earnings_calendar",
+		OutDir:  t.TempDir(),
+		Parameters: []map[string]interface{}{
+			{"start_date": "2024-01-22"},
+			{"end_date": "2024-01-22"},
+			{"tabs": []interface{}{"splits"}},
+		},
+	}
+
+	err = RunEarningsCalendar(job, client, output.NewRun())
+
+	var drift *SchemaDriftError
+	if !errors.As(err, &drift) {
+		t.Fatalf("expected schema drift, got %v", err)
+	}
+	if drift.Tab != "splits" || len(drift.Observed) != 4 {
+		t.Fatalf("unexpected drift: %+v", drift)
+	}
+
+	if _, err := os.Stat(drift.Payload); err != nil {
+		t.Fatalf("expected the payload to be saved: %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package earningscalendar
+
+import (
+	"strings"
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/output"
+	"github.com/iamburbo/zacks-scraper/util"
+)
+
+// A row of the events dataset, one per symbol, day and event type across
+// the calendar tabs. The details_ columns are the details of the event:
+// each event type fills the ones that apply to it and leaves the others
+// nil. Numbers are parsed, so -- and NA become nil
+type EventRow struct {
+	Symbol    string    `parquet:"name=symbol, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Company   string    `parquet:"name=company, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Date      time.Time `parquet:"name=date, type=INT32, convertedtype=DATE"`
+	EventType string    `parquet:"name=event_type, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	MarketCap *float64  `parquet:"name=market_cap, type=DOUBLE"` // millions
+
+	// Earnings and sales
+	Time           *string  `parquet:"name=details_time, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Estimate       *float64 `parquet:"name=details_estimate, type=DOUBLE"`
+	Reported       *float64 `parquet:"name=details_reported, type=DOUBLE"`
+	Surprise       *float64 `parquet:"name=details_surprise, type=DOUBLE"`
+	SurprisePct    *float64 `parquet:"name=details_surprise_pct, type=DOUBLE"`
+	PriceChangePct *float64 `parquet:"name=details_price_change_pct, type=DOUBLE"`
+
+	// Guidance and revisions. A symbol can have several periods on the same
+	// day: the details are those of the first one Zacks lists, and periods
+	// lists them all, e.g. "Q 3/2024; FY 12/2024"
+	Period    *string  `parquet:"name=details_period, type=BYTE_ARRAY, convertedtype=UTF8"`
+	PeriodEnd *string  `parquet:"name=details_period_end, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Periods   *string  `parquet:"name=details_periods, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Consensus *float64 `parquet:"name=details_consensus, type=DOUBLE"`
+
+	// Guidance
+	GuidanceLow    *float64 `parquet:"name=details_guidance_low, type=DOUBLE"`
+	GuidanceHigh   *float64 `parquet:"name=details_guidance_high, type=DOUBLE"`
+	GuidanceMid    *float64 `parquet:"name=details_guidance_mid, type=DOUBLE"`
+	ToHighPointPct *float64 `parquet:"name=details_to_high_point_pct, type=DOUBLE"`
+
+	// Revisions
+	OldEstimate       *float64 `parquet:"name=details_old_estimate, type=DOUBLE"`
+	NewEstimate       *float64 `parquet:"name=details_new_estimate, type=DOUBLE"`
+	EstimateChangePct *float64 `parquet:"name=details_estimate_change_pct, type=DOUBLE"`
+	NewVsConsensusPct *float64 `parquet:"name=details_new_vs_consensus_pct, type=DOUBLE"`
+
+	// Dividends and splits
+	Price *float64 `parquet:"name=details_price, type=DOUBLE"`
+
+	// Dividends
+	DividendAmount *float64   `parquet:"name=details_dividend_amount, type=DOUBLE"`
+	DividendYield  *float64   `parquet:"name=details_dividend_yield, type=DOUBLE"` // percent
+	ExDividendDate *time.Time `parquet:"name=details_ex_dividend_date, type=INT32, convertedtype=DATE"`
+	PayableDate    *time.Time `parquet:"name=details_payable_date, type=INT32, convertedtype=DATE"`
+
+	// Splits
+	SplitFactor *string `parquet:"name=details_split_factor, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+// Event type of the rows of each tab. Transcripts aren't events
+var tabEventTypes = map[string]string{
+	"earnings":  "earnings",
+	"sales":     "sales",
+	"guidance":  "guidance",
+	"revisions": "revision",
+	"dividends": "dividend",
+	"splits":    "split",
+}
+
+// Turns the rows of a tab queried for a day into events, one per symbol
+func tabEvents(tab string, date time.Time, data *earningsCalendarRawData) []*EventRow {
+	eventType, ok := tabEventTypes[tab]
+	if !ok {
+		return nil
+	}
+
+	event := func(symbol, company, marketCap string) *EventRow {
+		return &EventRow{
+			Symbol:    symbol,
+			Company:   company,
+			Date:      date,
+			EventType: eventType,
+			MarketCap: eventNumber(marketCap),
+		}
+	}
+
+	events := []*EventRow{}
+	switch tab {
+	case "earnings":
+		for _, r := range parseEarningsData(data) {
+			e := event(r.Symbol, r.Company, r.MarketCap)
+			e.Time = eventText(r.Time)
+			e.Estimate = eventNumber(r.Estimate)
+			e.Reported = eventNumber(r.Reported)
+			e.Surprise = eventNumber(r.Surprise)
+			e.SurprisePct = eventNumber(r.PercentSurp)
+			e.PriceChangePct = eventNumber(r.PercentPriceChange)
+			events = append(events, e)
+		}
+	case "sales":
+		for _, r := range parseSalesData(data) {
+			e := event(r.Symbol, r.Company, r.MarketCap)
+			e.Time = eventText(r.Time)
+			e.Estimate = eventNumber(r.Estimate)
+			e.Reported = eventNumber(r.Reported)
+			e.Surprise = eventNumber(r.Surprise)
+			e.SurprisePct = eventNumber(r.PercentSurp)
+			e.PriceChangePct = eventNumber(r.PercentPriceChange)
+			events = append(events, e)
+		}
+	case "guidance":
+		for _, r := range parseGuidanceData(data) {
+			e := event(r.Symbol, r.Company, r.MarketCap)
+			e.Period = eventText(r.Period)
+			e.PeriodEnd = eventText(r.PeriodEnd)
+			e.Consensus = eventNumber(r.Cons)
+			e.GuidanceLow, e.GuidanceHigh = guidanceRange(r.GuidRange)
+			e.GuidanceMid = eventNumber(r.MidGuid)
+			e.ToHighPointPct = eventNumber(r.PercentToHighPoint)
+			events = append(events, e)
+		}
+	case "revisions":
+		for _, r := range parseRevisionsData(data) {
+			e := event(r.Symbol, r.Company, r.MarketCap)
+			e.Period = eventText(r.Period)
+			e.PeriodEnd = eventText(r.PeriodEnd)
+			e.Consensus = eventNumber(r.Cons)
+			e.OldEstimate = eventNumber(r.Old)
+			e.NewEstimate = eventNumber(r.New)
+			e.EstimateChangePct = eventNumber(r.EstChange)
+			e.NewVsConsensusPct = eventNumber(r.NewEstVsCons)
+			events = append(events, e)
+		}
+	case "dividends":
+		for _, r := range parseDividendsData(data) {
+			e := event(r.Symbol, r.Company, r.MarketCap)
+			e.Price = eventNumber(r.CurrentPrice)
+			e.DividendAmount = eventNumber(r.Amount)
+			e.DividendYield = eventNumber(r.Yield)
+			e.ExDividendDate = eventDate(r.ExDivDate)
+			e.PayableDate = eventDate(r.PayableDate)
+			events = append(events, e)
+		}
+	case "splits":
+		for _, r := range parseSplitsData(data) {
+			e := event(r.Symbol, r.Company, r.MarketCap)
+			e.Price = eventNumber(r.Price)
+			e.SplitFactor = eventText(r.SplitFactor)
+			events = append(events, e)
+		}
+	}
+
+	return mergePeriods(events)
+}
+
+// Merges the events of a symbol's periods into the first of them, which
+// keeps its details and lists every period. Events without a period are
+// left as they are
+func mergePeriods(events []*EventRow) []*EventRow {
+	merged := []*EventRow{}
+	first := map[string]*EventRow{}
+	for _, e := range events {
+		if e.Period == nil && e.PeriodEnd == nil {
+			merged = append(merged, e)
+			continue
+		}
+
+		period := strings.TrimSpace(textOf(e.Period) + " " + textOf(e.PeriodEnd))
+		if f, ok := first[e.Symbol]; ok {
+			periods := *f.Periods + "; " + period
+			f.Periods = &periods
+			continue
+		}
+
+		e.Periods = &period
+		first[e.Symbol] = e
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+func textOf(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// Parses a number as shown by Zacks, e.g. $0.24, 2,955,390.00 or +3.81%
+func eventNumber(s string) *float64 {
+	f, err := output.ParseNumber(util.CellText(s))
+	if err != nil {
+		return nil
+	}
+	return f
+}
+
+// Parses a date as shown by Zacks, e.g. 1/22/2024
+func eventDate(s string) *time.Time {
+	d, err := time.Parse("1/2/2006", strings.TrimSpace(s))
+	if err != nil {
+		return nil
+	}
+	return &d
+}
+
+func eventText(s string) *string {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "--" || s == "NA" {
+		return nil
+	}
+	return &s
+}
+
+// Splits a guidance range, e.g. "3.10 - 3.35"
+func guidanceRange(s string) (*float64, *float64) {
+	parts := strings.SplitN(s, " - ", 2)
+	if len(parts) != 2 {
+		return nil, nil
+	}
+	return eventNumber(parts[0]), eventNumber(parts[1])
+}
@@ -1,13 +1,7 @@
 package earningscalendar
 
 import (
-	"fmt"
-	"log"
-	"os"
-
 	"github.com/iamburbo/zacks-scraper/util"
-	"github.com/xitongsys/parquet-go/parquet"
-	"github.com/xitongsys/parquet-go/writer"
 )
 
 type GuidanceDataRow struct {
@@ -34,8 +28,8 @@ func parseGuidanceData(rawData *earningsCalendarRawData) []*GuidanceDataRow {
 
 func parseGuidanceEntry(entry dataEntry) *GuidanceDataRow {
 
-	symbol := util.FindStringInBetween(`<span class="hoverquote-symbol">`, `<span class="sr-only">`, entry[0])
-	company := util.FindStringInBetween(`<span title="`, `" >`, entry[1])
+	symbol := util.CellClassText(entry[0], "hoverquote-symbol")
+	company := util.CellAttr(entry[1], "title")
 	marketCap := entry[2]
 	period := entry[3]
 	periodEnd := entry[4]
@@ -56,25 +50,3 @@ func parseGuidanceEntry(entry dataEntry) *GuidanceDataRow {
 		PercentToHighPoint: percentToHighPoint,
 	}
 }
-
-func writeGuidanceData(w *os.File, data []*GuidanceDataRow) error {
-	pw, err := writer.NewParquetWriterFromWriter(w, new(GuidanceDataRow), 4)
-	if err != nil {
-		return fmt.Errorf("can't create parquet writer: %e", err)
-	}
-
-	pw.RowGroupSize = 128 * 1024 * 1024 //128M
-	pw.CompressionType = parquet.CompressionCodec_SNAPPY
-
-	for _, row := range data {
-		if err = pw.Write(row); err != nil {
-			log.Println("Write error", err)
-		}
-	}
-
-	if err = pw.WriteStop(); err != nil {
-		log.Println("WriteStop error", err)
-	}
-
-	return nil
-}
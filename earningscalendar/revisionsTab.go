@@ -1,13 +1,7 @@
 package earningscalendar
 
 import (
-	"fmt"
-	"log"
-	"os"
-
 	"github.com/iamburbo/zacks-scraper/util"
-	"github.com/xitongsys/parquet-go/parquet"
-	"github.com/xitongsys/parquet-go/writer"
 )
 
 type RevisionsDataRow struct {
@@ -36,29 +30,19 @@ func parseRevisionsData(rawData *earningsCalendarRawData) []*RevisionsDataRow {
 
 func parseRevisionsEntry(entry dataEntry) *RevisionsDataRow {
 
-	symbol := util.FindStringInBetween(`<span class="hoverquote-symbol">`, `<span class="sr-only">`, entry[0])
-	company := util.FindStringInBetween(`<span title="`, `" >`, entry[1])
+	symbol := util.CellClassText(entry[0], "hoverquote-symbol")
+	company := util.CellAttr(entry[1], "title")
 	marketCap := entry[2]
 	period := entry[3]
 	periodEnd := entry[4]
 	old := entry[5]
 	new := entry[6]
 
-	var estChange string
-	if entry[7] != "NA" {
-		estChange = util.FindStringInBetween(`">`, `</div>`, entry[7])
-	} else {
-		estChange = entry[7]
-	}
+	estChange := util.CellText(entry[7])
 
 	cons := entry[8]
 
-	var newEstVsCons string
-	if entry[9] != "NA" {
-		newEstVsCons = util.FindStringInBetween(`">`, `</div>`, entry[9])
-	} else {
-		newEstVsCons = entry[9]
-	}
+	newEstVsCons := util.CellText(entry[9])
 
 	return &RevisionsDataRow{
 		Symbol:       symbol,
@@ -73,25 +57,3 @@ func parseRevisionsEntry(entry dataEntry) *RevisionsDataRow {
 		NewEstVsCons: newEstVsCons,
 	}
 }
-
-func writeRevisionsData(w *os.File, data []*RevisionsDataRow) error {
-	pw, err := writer.NewParquetWriterFromWriter(w, new(RevisionsDataRow), 4)
-	if err != nil {
-		return fmt.Errorf("can't create parquet writer: %e", err)
-	}
-
-	pw.RowGroupSize = 128 * 1024 * 1024 //128M
-	pw.CompressionType = parquet.CompressionCodec_SNAPPY
-
-	for _, row := range data {
-		if err = pw.Write(row); err != nil {
-			log.Println("Write error", err)
-		}
-	}
-
-	if err = pw.WriteStop(); err != nil {
-		log.Println("WriteStop error", err)
-	}
-
-	return nil
-}
@@ -1,13 +1,7 @@
 package earningscalendar
 
 import (
-	"fmt"
-	"log"
-	"os"
-
 	"github.com/iamburbo/zacks-scraper/util"
-	"github.com/xitongsys/parquet-go/parquet"
-	"github.com/xitongsys/parquet-go/writer"
 )
 
 type SalesDataRow struct {
@@ -34,28 +28,18 @@ func parseSalesData(rawData *earningsCalendarRawData) []*SalesDataRow {
 
 func parseSalesEntry(entry dataEntry) *SalesDataRow {
 
-	symbol := util.FindStringInBetween(`<span class="hoverquote-symbol">`, `<span class="sr-only">`, entry[0])
-	company := util.FindStringInBetween(`<span title="`, `" >`, entry[1])
+	symbol := util.CellClassText(entry[0], "hoverquote-symbol")
+	company := util.CellAttr(entry[1], "title")
 	marketCap := entry[2]
 	time := entry[3]
 	estimate := entry[4]
 	reported := entry[5]
 
-	var surprise string
-	if entry[6] != "--" {
-		surprise = util.FindStringInBetween(`">`, `</div>`, entry[6])
-	} else {
-		surprise = entry[6]
-	}
+	surprise := util.CellText(entry[6])
 
-	var percentSurp string
-	if entry[7] != "--" {
-		percentSurp = util.FindStringInBetween(`">`, `</div>`, entry[7])
-	} else {
-		percentSurp = entry[7]
-	}
+	percentSurp := util.CellText(entry[7])
 
-	percentPriceChange := util.FindStringInBetween(`">`, `</div>`, entry[8])
+	percentPriceChange := util.CellText(entry[8])
 
 	return &SalesDataRow{
 		Symbol:             symbol,
@@ -69,25 +53,3 @@ func parseSalesEntry(entry dataEntry) *SalesDataRow {
 		PercentPriceChange: percentPriceChange,
 	}
 }
-
-func writeSalesData(w *os.File, data []*SalesDataRow) error {
-	pw, err := writer.NewParquetWriterFromWriter(w, new(SalesDataRow), 4)
-	if err != nil {
-		return fmt.Errorf("can't create parquet writer: %e", err)
-	}
-
-	pw.RowGroupSize = 128 * 1024 * 1024 //128M
-	pw.CompressionType = parquet.CompressionCodec_SNAPPY
-
-	for _, row := range data {
-		if err = pw.Write(row); err != nil {
-			log.Println("Write error", err)
-		}
-	}
-
-	if err = pw.WriteStop(); err != nil {
-		log.Println("WriteStop error", err)
-	}
-
-	return nil
-}
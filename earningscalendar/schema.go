@@ -0,0 +1,140 @@
+package earningscalendar
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/iamburbo/zacks-scraper/util"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Signature of a cell in a calendar row
+type cellKind string
+
+const (
+	cellSymbol cellKind = "symbol" // hoverquote symbol link
+	cellTitle  cellKind = "title"  // company name span with a title
+	cellText   cellKind = "text"   // plain text without markup
+	cellValue  cellKind = "value"  // plain text, or text in an up/down colored div
+	cellLink   cellKind = "link"   // a link, e.g. to a transcript
+	cellMarkup cellKind = "markup" // any other markup, only ever observed
+)
+
+// Expected columns of each tab, in order
+var tabSchemas = map[string][]cellKind{
+	"earnings":    {cellSymbol, cellTitle, cellText, cellText, cellText, cellText, cellValue, cellValue, cellValue},
+	"sales":       {cellSymbol, cellTitle, cellText, cellText, cellText, cellText, cellValue, cellValue, cellValue},
+	"guidance":    {cellSymbol, cellTitle, cellText, cellText, cellText, cellText, cellText, cellText, cellText},
+	"revisions":   {cellSymbol, cellTitle, cellText, cellText, cellText, cellText, cellText, cellValue, cellText, cellValue},
+	"dividends":   {cellSymbol, cellTitle, cellText, cellText, cellText, cellText, cellText, cellText},
+	"splits":      {cellSymbol, cellTitle, cellText, cellText, cellText},
+	"transcripts": {cellSymbol, cellTitle, cellText, cellText, cellLink},
+}
+
+// Returned when a calendar payload doesn't have the shape its tab parser
+// expects, e.g. because Zacks added, removed or reordered a column
+type SchemaDriftError struct {
+	Tab      string
+	Row      int        // index of the first row that didn't match
+	Expected []cellKind // expected cell signatures
+	Observed []cellKind // cell signatures of the row
+	Payload  string     // where the raw payload was saved, if it was
+}
+
+func (e *SchemaDriftError) Error() string {
+	msg := fmt.Sprintf("schema drift in %v tab: row %v has %v columns %v, expected %v columns %v",
+		e.Tab, e.Row, len(e.Observed), joinKinds(e.Observed), len(e.Expected), joinKinds(e.Expected))
+	if e.Payload != "" {
+		msg += fmt.Sprintf(" (raw payload saved to %v)", e.Payload)
+	}
+	return msg
+}
+
+// Checks every row of a payload against the tab's schema before parsing
+func validateTabData(tab string, data *earningsCalendarRawData) error {
+	expected, ok := tabSchemas[tab]
+	if !ok {
+		return fmt.Errorf("unknown tab: %v", tab)
+	}
+
+	for i, entry := range data.Data {
+		observed := make([]cellKind, len(entry))
+		for j, cell := range entry {
+			observed[j] = classifyCell(cell)
+		}
+
+		if !matchesSchema(expected, observed) {
+			return &SchemaDriftError{
+				Tab:      tab,
+				Row:      i,
+				Expected: expected,
+				Observed: observed,
+			}
+		}
+	}
+
+	return nil
+}
+
+func matchesSchema(expected, observed []cellKind) bool {
+	if len(expected) != len(observed) {
+		return false
+	}
+
+	for i, kind := range expected {
+		switch {
+		case kind == observed[i]:
+		case kind == cellValue && observed[i] == cellText:
+		case kind == cellLink && observed[i] == cellText: // nothing to link to yet
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the most specific signature a cell matches
+func classifyCell(cell string) cellKind {
+	nodes := util.ParseFragment(cell)
+
+	hasElement := util.FindElement(nodes, func(n *html.Node) bool { return true }) != nil
+	if !hasElement {
+		return cellText
+	}
+
+	symbol := util.FindElement(nodes, func(n *html.Node) bool {
+		return util.HasClass(n, "hoverquote-symbol")
+	})
+	if symbol != nil {
+		return cellSymbol
+	}
+
+	title := util.FindElement(nodes, func(n *html.Node) bool {
+		_, ok := util.Attr(n, "title")
+		return ok
+	})
+	if title != nil {
+		return cellTitle
+	}
+
+	if len(nodes) == 1 && nodes[0].DataAtom == atom.A {
+		if _, ok := util.Attr(nodes[0], "href"); ok {
+			return cellLink
+		}
+	}
+
+	if len(nodes) == 1 && nodes[0].Data == "div" {
+		return cellValue
+	}
+
+	return cellMarkup
+}
+
+func joinKinds(kinds []cellKind) string {
+	s := make([]string, len(kinds))
+	for i, k := range kinds {
+		s[i] = string(k)
+	}
+	return "[" + strings.Join(s, " ") + "]"
+}
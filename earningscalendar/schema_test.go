@@ -0,0 +1,62 @@
+package earningscalendar
+
+import (
+	"errors"
+	"testing"
+)
+
+const (
+	symbolCell = `<a href="/stock/quote/AAPL" rel="AAPL" class="hoverquote-container-od"><span class="hoverquote-symbol">AAPL<span class="sr-only"></span></span></a>`
+	titleCell  = `<span title="Apple Inc." >Apple Inc.</span>`
+	divCell    = `<div class="right pos positive pos_plus">0.08</div>`
+)
+
+func TestValidateTabData(t *testing.T) {
+	cases := []struct {
+		name  string
+		entry dataEntry
+		drift bool
+	}{
+		{"matches", dataEntry{symbolCell, titleCell, "2,955,390.00", "After Close", "2.10", "2.18", divCell, divCell, divCell}, false},
+		{"not yet reported", dataEntry{symbolCell, titleCell, "408,220.00", "Before Open", "2.20", "--", "--", "--", divCell}, false},
+		{"dropped column", dataEntry{symbolCell, titleCell, "After Close", "2.10", "2.18", divCell, divCell, divCell}, true},
+		{"added column", dataEntry{symbolCell, titleCell, "2,955,390.00", "After Close", "2.10", "2.18", divCell, divCell, divCell, "x"}, true},
+		{"reordered columns", dataEntry{titleCell, symbolCell, "2,955,390.00", "After Close", "2.10", "2.18", divCell, divCell, divCell}, true},
+		{"markup in text column", dataEntry{symbolCell, titleCell, divCell, "After Close", "2.10", "2.18", divCell, divCell, divCell}, true},
+	}
+
+	for _, c := range cases {
+		data := &earningsCalendarRawData{Data: []dataEntry{c.entry}}
+		err := validateTabData("earnings", data)
+
+		var drift *SchemaDriftError
+		if errors.As(err, &drift) != c.drift {
+			t.Fatalf("%v: unexpected error: %v", c.name, err)
+		}
+		if c.drift && (drift.Tab != "earnings" || len(drift.Observed) != len(c.entry)) {
+			t.Fatalf("%v: unexpected drift: %+v", c.name, drift)
+		}
+	}
+}
+
+func TestValidateTranscriptsData(t *testing.T) {
+	cases := []struct {
+		name  string
+		entry dataEntry
+		drift bool
+	}{
+		{"link", dataEntry{symbolCell, titleCell, "2,955,390.00", "Q1 2024 Earnings Call", `<a href="/stock/research/AAPL/earnings-call-transcripts">Transcript</a>`}, false},
+		{"no link yet", dataEntry{symbolCell, titleCell, "2,955,390.00", "Q1 2024 Earnings Call", ""}, false},
+		{"markup instead of link", dataEntry{symbolCell, titleCell, "2,955,390.00", "Q1 2024 Earnings Call", divCell}, true},
+	}
+
+	for _, c := range cases {
+		data := &earningsCalendarRawData{Data: []dataEntry{c.entry}}
+		err := validateTabData("transcripts", data)
+
+		var drift *SchemaDriftError
+		if errors.As(err, &drift) != c.drift {
+			t.Fatalf("%v: unexpected error: %v", c.name, err)
+		}
+	}
+}
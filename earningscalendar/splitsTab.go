@@ -1,13 +1,7 @@
 package earningscalendar
 
 import (
-	"fmt"
-	"log"
-	"os"
-
 	"github.com/iamburbo/zacks-scraper/util"
-	"github.com/xitongsys/parquet-go/parquet"
-	"github.com/xitongsys/parquet-go/writer"
 )
 
 type SplitsDataRow struct {
@@ -31,8 +25,8 @@ func parseSplitsData(rawData *earningsCalendarRawData) []*SplitsDataRow {
 
 func parseSplitsEntry(entry dataEntry) *SplitsDataRow {
 
-	symbol := util.FindStringInBetween(`<span class="hoverquote-symbol">`, `<span class="sr-only">`, entry[0])
-	company := util.FindStringInBetween(`<span title="`, `" >`, entry[1])
+	symbol := util.CellClassText(entry[0], "hoverquote-symbol")
+	company := util.CellAttr(entry[1], "title")
 	marketCap := entry[2]
 	price := entry[3]
 	splitFactor := entry[4]
@@ -45,25 +39,3 @@ func parseSplitsEntry(entry dataEntry) *SplitsDataRow {
 		SplitFactor: splitFactor,
 	}
 }
-
-func writeSplitsData(w *os.File, data []*SplitsDataRow) error {
-	pw, err := writer.NewParquetWriterFromWriter(w, new(SplitsDataRow), 4)
-	if err != nil {
-		return fmt.Errorf("can't create parquet writer: %e", err)
-	}
-
-	pw.RowGroupSize = 128 * 1024 * 1024 //128M
-	pw.CompressionType = parquet.CompressionCodec_SNAPPY
-
-	for _, row := range data {
-		if err = pw.Write(row); err != nil {
-			log.Println("Write error", err)
-		}
-	}
-
-	if err = pw.WriteStop(); err != nil {
-		log.Println("WriteStop error", err)
-	}
-
-	return nil
-}
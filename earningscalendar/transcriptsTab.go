@@ -0,0 +1,210 @@
+package earningscalendar
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/archive"
+	"github.com/iamburbo/zacks-scraper/config"
+	"github.com/iamburbo/zacks-scraper/output"
+	"github.com/iamburbo/zacks-scraper/util"
+	"github.com/iamburbo/zacks-scraper/zacks"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+type TranscriptsDataRow struct {
+	Symbol        string `parquet:"name=symbol, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Company       string `parquet:"name=company, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	MarketCap     string `parquet:"name=marketCap, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Event         string `parquet:"name=event, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	TranscriptURL string `parquet:"name=transcriptUrl, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+}
+
+// Text of a transcript, downloaded from its link in the transcripts tab
+type TranscriptTextRow struct {
+	Symbol        string `parquet:"name=symbol, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Event         string `parquet:"name=event, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	TranscriptURL string `parquet:"name=transcriptUrl, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Text          string `parquet:"name=text, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+func parseTranscriptsData(rawData *earningsCalendarRawData) []*TranscriptsDataRow {
+	rows := []*TranscriptsDataRow{}
+
+	for _, entry := range rawData.Data {
+		row := parseTranscriptsEntry(entry)
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func parseTranscriptsEntry(entry dataEntry) *TranscriptsDataRow {
+
+	symbol := util.CellClassText(entry[0], "hoverquote-symbol")
+	company := util.CellAttr(entry[1], "title")
+	marketCap := entry[2]
+	event := entry[3]
+	transcriptURL := transcriptLink(util.CellAttr(entry[4], "href"))
+
+	return &TranscriptsDataRow{
+		Symbol:        symbol,
+		Company:       company,
+		MarketCap:     marketCap,
+		Event:         event,
+		TranscriptURL: transcriptURL,
+	}
+}
+
+// Links in the calendar are relative to the Zacks site
+func transcriptLink(href string) string {
+	if href == "" {
+		return ""
+	}
+
+	base, err := url.Parse(zacks.WWW + "/")
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// Downloads the page of a transcript
+func getTranscriptPage(transcriptURL string, client *http.Client) ([]byte, *archive.Exchange, error) {
+	req, err := http.NewRequest("GET", transcriptURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req.Header.Set("user-agent", `Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/106.0.0.0 Safari/537.36`)
+	req.Header.Set("accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+
+	fetchedAt := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case 200:
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return bodyBytes, archive.NewExchange(req, nil, resp, fetchedAt), nil
+	default:
+		return nil, nil, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+}
+
+// Extracts the text of a transcript page. The transcript is the page's
+// <article>, one paragraph per line
+func parseTranscriptText(body []byte) (string, error) {
+	nodes := util.ParseFragment(string(body))
+	article := util.FindElement(nodes, func(n *html.Node) bool {
+		return n.DataAtom == atom.Article
+	})
+	if article == nil {
+		return "", errors.New("no transcript found in page")
+	}
+
+	paragraphs := []string{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.P {
+			if text := util.Text(n); text != "" {
+				paragraphs = append(paragraphs, text)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(article)
+
+	if len(paragraphs) == 0 {
+		return util.Text(article), nil
+	}
+	return strings.Join(paragraphs, "\n"), nil
+}
+
+// Downloads the text of each transcript listed on a day and writes them as
+// a dataset of their own. Transcripts that can't be fetched or parsed are
+// left out with a warning, so one page doesn't fail the calendar
+func writeTranscriptTexts(job *config.ScrapeJob, client *http.Client, run *output.Run, date time.Time, transcripts []*TranscriptsDataRow) error {
+	rows := []*TranscriptTextRow{}
+	var scrapedAt time.Time
+
+	for _, t := range transcripts {
+		if t.TranscriptURL == "" {
+			continue
+		}
+
+		body, exchange, err := run.Fetch(job, archive.Entry{
+			Kind: archive.KindTranscript,
+			Date: date.Format("2006-01-02"),
+			Tab:  t.Symbol,
+		}, func() ([]byte, *archive.Exchange, error) {
+			return getTranscriptPage(t.TranscriptURL, client)
+		})
+		if err != nil {
+			log.Printf("skipping %v transcript: %v", t.Symbol, err)
+			continue
+		}
+
+		text, err := parseTranscriptText(body)
+		if err != nil {
+			log.Printf("skipping %v transcript: %v", t.Symbol, err)
+			continue
+		}
+
+		if scrapedAt.IsZero() {
+			scrapedAt = exchange.FetchedAt
+		}
+		rows = append(rows, &TranscriptTextRow{
+			Symbol:        t.Symbol,
+			Event:         t.Event,
+			TranscriptURL: t.TranscriptURL,
+			Text:          text,
+		})
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	table, err := output.NewTable("earnings_calendar.transcript_text", rows)
+	if err != nil {
+		return err
+	}
+
+	return run.WriteTable(job, output.Partition{
+		Dataset:      "earnings_call_transcripts",
+		Tab:          "transcript_text",
+		Date:         date,
+		Ext:          "parquet",
+		FlatTemplate: "{run_time}/{timestamp}_transcript_text.{ext}",
+		Endpoint:     zacks.WWW,
+		ScrapedAt:    scrapedAt,
+		Rules: []output.Rule{
+			output.SymbolFormat("symbol"),
+		},
+
+		// Written alongside the transcripts tab
+		SkipMissingColumns: true,
+	}, table)
+}
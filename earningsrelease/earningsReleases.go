@@ -1,40 +1,38 @@
 package earningsrelease
 
 import (
-	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
 	"strconv"
-	"strings"
 	"time"
 
+	"github.com/iamburbo/zacks-scraper/archive"
 	"github.com/iamburbo/zacks-scraper/config"
-	"github.com/xitongsys/parquet-go/parquet"
-	"github.com/xitongsys/parquet-go/writer"
+	"github.com/iamburbo/zacks-scraper/output"
+	"github.com/iamburbo/zacks-scraper/zacks"
 )
 
+// Each tab of the export, by its tab_id
+var EarningsReleaseTabs = map[string]int{
+	"earnings":  1,
+	"sales":     9,
+	"guidance":  6,
+	"revisions": 3,
+	"dividends": 5,
+	"splits":    4,
+}
+
 type EarningsReleaseParams struct {
 	start_date time.Time
 	end_date   time.Time
+	tabs       []string
 }
 
-type RawEarningsReleaseRow struct {
-	Symbol             string `parquet:"name=symbol, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
-	Company            string `parquet:"name=company, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
-	ReportTime         string `parquet:"name=reportTime, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
-	Estimate           string `parquet:"name=estimate, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
-	Reported           string `parquet:"name=reported, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
-	Surprise           string `parquet:"name=surprise, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
-	CurrentPrice       string `parquet:"name=currentPrice, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
-	PricePercentChange string `parquet:"name=pricePercentChange, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
-}
-
-func RunEarningsRelease(job *config.ScrapeJob, client *http.Client) error {
+func RunEarningsRelease(job *config.ScrapeJob, client *http.Client, run *output.Run) error {
 	params, err := parseJobParameters(job.Parameters)
 	if err != nil {
 		return fmt.Errorf("error parsing parameters: %e", err)
@@ -42,24 +40,48 @@ func RunEarningsRelease(job *config.ScrapeJob, client *http.Client) error {
 
 	temp := params.start_date
 	for params.end_date.Sub(temp) >= 0 {
-		// Fetch data
-		body, err := getEarningsRelease(temp, client)
-		if err != nil {
-			return err
-		}
-
-		// Parse data
-		parsedRows := parseEarningReleaseBody(body, temp)
+		for _, tab := range params.tabs {
+			// Fetch data
+			body, exchange, err := run.Fetch(job, archive.Entry{
+				Kind: archive.KindReleaseTSV,
+				Date: temp.Format("2006-01-02"),
+				Tab:  archiveTab(tab),
+			}, func() ([]byte, *archive.Exchange, error) {
+				return getEarningsRelease(temp, tab, client)
+			})
+			if errors.Is(err, output.ErrNotArchived) {
+				log.Printf("skipping earnings release: %v", err)
+				continue
+			}
+			if err != nil {
+				return err
+			}
 
-		// Write to parquet
-		filenameTimestamp := temp.Add(-time.Hour * 1)
-		fileName := filenameTimestamp.Format("20060102150405") + ".parquet"
-		w, err := os.Create(filepath.Join(job.OutDir, fileName))
-		if err != nil {
-			return fmt.Errorf("failed to create local file: %e", err)
+			// Parse data
+			records, dropped, err := parseEarningReleaseBody(body, requiredColumns[tab])
+			if err != nil {
+				return fmt.Errorf("error parsing %v export: %w", tab, err)
+			}
+			for _, d := range dropped {
+				log.Printf("dropped earnings release %v row on line %v: %v", tab, d.Line, d.Reason)
+			}
+			table := output.TableFromRecords("earnings_release."+tab, records)
+
+			err = run.WriteTable(job, output.Partition{
+				Tab:          tab,
+				Date:         temp.Add(-time.Hour * 1),
+				Ext:          "parquet",
+				FlatTemplate: flatTemplate(tab),
+				Endpoint:     zacks.EarningsExportURL(),
+				ScrapedAt:    exchange.FetchedAt,
+				Rules:        tabRules(tab),
+
+				SkipMissingColumns: len(params.tabs) > 1,
+			}, table)
+			if err != nil {
+				return err
+			}
 		}
-		writeToParquet(w, parsedRows)
-		w.Close()
 
 		// Move on to next day
 		temp = temp.Add(24 * time.Hour)
@@ -68,9 +90,42 @@ func RunEarningsRelease(job *config.ScrapeJob, client *http.Client) error {
 	return nil
 }
 
+// The earnings tab was the only one before tabs could be chosen, and keeps
+// its file names and archive keys so older outputs and archives line up
+func flatTemplate(tab string) string {
+	if tab == "earnings" {
+		return "{timestamp}.{ext}"
+	}
+	return "{timestamp}_{tab}.{ext}"
+}
+
+func archiveTab(tab string) string {
+	if tab == "earnings" {
+		return ""
+	}
+	return tab
+}
+
+// Data quality checks for the rows of a tab. Checks of columns a tab
+// doesn't have are skipped
+func tabRules(tab string) []output.Rule {
+	rules := []output.Rule{
+		output.SymbolFormat("symbol"),
+		output.UniqueSymbol("symbol"),
+	}
+
+	switch tab {
+	case "earnings", "sales":
+		rules = append(rules, output.NumericEstimate("estimate"))
+	}
+
+	return rules
+}
+
 func parseJobParameters(parameters []map[string]interface{}) (*EarningsReleaseParams, error) {
 	var start_date time.Time
 	var end_date time.Time
+	var tabs []string
 
 	for _, p := range parameters {
 		if t, ok := p["start_date"]; ok {
@@ -102,37 +157,57 @@ func parseJobParameters(parameters []map[string]interface{}) (*EarningsReleasePa
 				end_date = parsed
 			}
 		}
+
+		if t, ok := p["tabs"]; ok {
+			list, ok := t.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("tabs must be a list, got %v", t)
+			}
+			for _, v := range list {
+				tab := fmt.Sprint(v)
+				if _, ok := EarningsReleaseTabs[tab]; !ok {
+					return nil, fmt.Errorf("unknown earnings release tab: %v", tab)
+				}
+				tabs = append(tabs, tab)
+			}
+		}
+	}
+
+	if len(tabs) == 0 {
+		tabs = []string{"earnings"}
 	}
 
 	return &EarningsReleaseParams{
 		start_date: start_date,
 		end_date:   end_date,
+		tabs:       tabs,
 	}, nil
 }
 
-func getEarningsRelease(timestamp time.Time, client *http.Client) ([]byte, error) {
-	u, err := url.Parse("https://www.zacks.com/research/earnings/earning_export.php")
+func getEarningsRelease(timestamp time.Time, tab string, client *http.Client) ([]byte, *archive.Exchange, error) {
+	u, err := url.Parse(zacks.EarningsExportURL())
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	q := u.Query()
 	q.Set("timestamp", strconv.Itoa(int(timestamp.Unix())))
-	q.Set("tab_id", "1")
+	q.Set("tab_id", strconv.Itoa(EarningsReleaseTabs[tab]))
 
 	u.RawQuery = q.Encode()
 
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	req.Header.Set("user-agent", `Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/106.0.0.0 Safari/537.36`)
 	req.Header.Set("accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.9")
 
+	fetchedAt := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
@@ -140,83 +215,11 @@ func getEarningsRelease(timestamp time.Time, client *http.Client) ([]byte, error
 	case 200:
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		return body, nil
+		return body, archive.NewExchange(req, nil, resp, fetchedAt), nil
 	default:
-		return nil, fmt.Errorf("status code %d", resp.StatusCode)
-	}
-
-}
-
-func parseEarningReleaseBody(body []byte, timestamp time.Time) []*RawEarningsReleaseRow {
-
-	parsedRows := []*RawEarningsReleaseRow{}
-
-	rows := strings.Split(string(body), "\n")
-	for _, row := range rows[1:] {
-		items := strings.Split(row, "\t")
-
-		if len(items) == 9 {
-			parsedItem := &RawEarningsReleaseRow{
-				Symbol:             items[0],
-				Company:            items[1],
-				ReportTime:         items[2],
-				Estimate:           items[3],
-				Reported:           items[4],
-				Surprise:           items[5],
-				CurrentPrice:       items[6],
-				PricePercentChange: items[7],
-			}
-
-			parsedRows = append(parsedRows, parsedItem)
-
-		}
-
-	}
-
-	return parsedRows
-}
-
-func writeParsedRowsToCSV(rows []*RawEarningsReleaseRow, outDir string, timestamp time.Time) {
-	fileName := timestamp.Format("20060102") + ".csv"
-	f, err := os.Create(filepath.Join(outDir, fileName))
-	if err != nil {
-		log.Fatalf("error creating output file: %v", err)
+		return nil, nil, fmt.Errorf("status code %d", resp.StatusCode)
 	}
-	w := csv.NewWriter(f)
-	defer w.Flush()
 
-	err = w.Write([]string{"Symbol", "Company", "Report Time", "Estimate", "Reported", "Surprise", "Current Price", "Price % Change"})
-	if err != nil {
-		log.Fatalln("error writing header record", err)
-	}
-
-	for _, record := range rows {
-		if err = w.Write([]string{record.Symbol, record.Company, record.ReportTime, record.Estimate, record.Reported, record.Surprise, record.CurrentPrice, record.PricePercentChange}); err != nil {
-			log.Fatalln("error writing record to file", err)
-		}
-	}
-}
-
-func writeToParquet(w *os.File, data []*RawEarningsReleaseRow) error {
-	pw, err := writer.NewParquetWriterFromWriter(w, new(RawEarningsReleaseRow), 4)
-	if err != nil {
-		return fmt.Errorf("can't create parquet writer: %e", err)
-	}
-
-	pw.RowGroupSize = 128 * 1024 * 1024 //128M
-	pw.CompressionType = parquet.CompressionCodec_SNAPPY
-
-	for _, row := range data {
-		if err = pw.Write(row); err != nil {
-			log.Println("Write error", err)
-		}
-	}
-
-	if err = pw.WriteStop(); err != nil {
-		log.Println("WriteStop error", err)
-	}
-
-	return nil
 }
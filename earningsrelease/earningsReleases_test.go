@@ -1,36 +1,120 @@
 package earningsrelease
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/iamburbo/zacks-scraper/config"
-	"github.com/iamburbo/zacks-scraper/util"
+	"github.com/iamburbo/zacks-scraper/output"
 	"github.com/iamburbo/zacks-scraper/zacks"
+	"github.com/iamburbo/zacks-scraper/zackstest"
 )
 
 func TestRunEarningsRelease(t *testing.T) {
-	cfg, err := config.LoadConfigFile("../config.yml")
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	client := util.GetTestClient()
+	job := &config.ScrapeJob{
+		JobType: "earnings_release",
+		OutDir:  t.TempDir(),
+		Parameters: []map[string]interface{}{
+			{"start_date": "2024-01-22"},
+			{"end_date": "2024-01-23"},
+		},
+	}
 
-	err = zacks.LogIn(client, cfg)
+	err = RunEarningsRelease(job, client, output.NewRun())
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	var job *config.ScrapeJob = nil
-	for _, j := range cfg.Jobs {
-		if j.JobType == "earnings_release" {
-			job = &j
-			break
-		}
+	files, err := filepath.Glob(filepath.Join(job.OutDir, "*.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %v", len(files))
 	}
 
-	err = RunEarningsRelease(job, client)
+	rows, err := zackstest.ReadParquet(files[0])
 	if err != nil {
 		t.Fatal(err)
 	}
+	if len(rows) == 0 {
+		t.Fatal("expected rows")
+	}
+	if rows[0]["symbol"] == "" {
+		t.Fatalf("expected a symbol, got %+v", rows[0])
+	}
+}
+
+func TestRunEarningsReleaseTabs(t *testing.T) {
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &config.ScrapeJob{
+		JobType: "earnings_release",
+		OutDir:  t.TempDir(),
+		Layout:  output.LayoutPartitioned,
+		Parameters: []map[string]interface{}{
+			{"start_date": "2024-01-22"},
+			{"end_date": "2024-01-22"},
+			{"tabs": []interface{}{"earnings", "sales", "dividends"}},
+		},
+	}
+
+	err = RunEarningsRelease(job, client, output.NewRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		tab    string
+		column string
+		value  string
+	}{
+		{"earnings", "pricePercentChange", "1.20%"},
+		{"sales", "estimate", "117,910.00"},
+		{"dividends", "exDivDate", "1/22/2024"},
+	}
+
+	for _, c := range cases {
+		files, err := filepath.Glob(filepath.Join(job.OutDir, "dataset=earnings_release", "tab="+c.tab, "*", "*.parquet"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 {
+			t.Fatalf("expected 1 %v file, got %v", c.tab, len(files))
+		}
+
+		rows, err := zackstest.ReadParquet(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) < 2 || rows[0][c.column] != c.value {
+			t.Fatalf("unexpected %v rows: %+v", c.tab, rows)
+		}
+	}
+
+	for _, tabID := range []string{"1", "9", "5"} {
+		found := false
+		for _, r := range server.RequestsTo("/research/earnings/earning_export.php") {
+			found = found || r.Query.Get("tab_id") == tabID
+		}
+		if !found {
+			t.Fatalf("expected a request for tab_id %v", tabID)
+		}
+	}
 }
@@ -0,0 +1,147 @@
+package earningsrelease
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Header names each tab's export must have. Other columns are kept as they
+// come
+var requiredColumns = map[string][]string{
+	"earnings":  {"Symbol", "Company", "Estimate", "Reported"},
+	"sales":     {"Symbol", "Company", "Estimate", "Reported"},
+	"guidance":  {"Symbol", "Company"},
+	"revisions": {"Symbol", "Company"},
+	"dividends": {"Symbol", "Company"},
+	"splits":    {"Symbol", "Company"},
+}
+
+// A row of the export that was left out, and why
+type droppedRow struct {
+	Line   int
+	Reason string
+}
+
+// Parses the export into records, header first. Columns are found by their
+// header name and named after it, e.g. "Price % Change" becomes
+// pricePercentChange. Cells may be quoted, and lines may end with CRLF.
+// Rows that don't fit the header are left out and returned with the
+// reason. An export missing a required column is an error
+func parseEarningReleaseBody(body []byte, required []string) ([][]string, []droppedRow, error) {
+	r := csv.NewReader(bytes.NewReader(body))
+	r.Comma = '\t'
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("empty export")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading header: %w", err)
+	}
+
+	// Lines end with a tab, so the header has unnamed cells at the end
+	positions := []int{}
+	names := []string{}
+	found := map[string]bool{}
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		name := columnName(h)
+		if name == "" {
+			continue
+		}
+		positions = append(positions, i)
+		names = append(names, name)
+		found[h] = true
+	}
+
+	if len(names) == 0 {
+		return nil, nil, fmt.Errorf("no columns in header %q", header)
+	}
+	for _, c := range required {
+		if !found[c] {
+			return nil, nil, fmt.Errorf("missing required column %q in header %q", c, header)
+		}
+	}
+
+	records := [][]string{names}
+	dropped := []droppedRow{}
+	for {
+		cells, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			dropped = append(dropped, droppedRow{Line: parseErr.StartLine, Reason: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Only valid once the record parsed
+		line, _ := r.FieldPos(0)
+		if reason := rowProblem(cells, header, positions); reason != "" {
+			dropped = append(dropped, droppedRow{Line: line, Reason: reason})
+			continue
+		}
+
+		record := make([]string, len(positions))
+		for i, p := range positions {
+			record[i] = cells[p]
+		}
+		records = append(records, record)
+	}
+
+	return records, dropped, nil
+}
+
+// Describes why a row doesn't fit the header, or returns "" if it does.
+// Unnamed columns must be empty
+func rowProblem(cells, header []string, positions []int) string {
+	last := positions[len(positions)-1]
+	if len(cells) <= last {
+		return fmt.Sprintf("%v cells, header has %v columns", len(cells), len(positions))
+	}
+
+	named := map[int]bool{}
+	for _, p := range positions {
+		named[p] = true
+	}
+	for i, c := range cells {
+		if !named[i] && strings.TrimSpace(c) != "" {
+			if i >= len(header) {
+				return fmt.Sprintf("%v cells, header has %v columns", len(cells), len(positions))
+			}
+			return fmt.Sprintf("value %q in unnamed column %v", c, i+1)
+		}
+	}
+
+	return ""
+}
+
+// Turns a header name into a column name, e.g. "Report Time" into
+// reportTime
+func columnName(header string) string {
+	header = strings.ReplaceAll(header, "%", " Percent ")
+	words := strings.FieldsFunc(header, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	name := ""
+	for i, w := range words {
+		if i == 0 {
+			name += strings.ToLower(w)
+		} else {
+			name += strings.ToUpper(w[:1]) + strings.ToLower(w[1:])
+		}
+	}
+	return name
+}
@@ -0,0 +1,99 @@
+package earningsrelease
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEarningReleaseBody(t *testing.T) {
+	required := requiredColumns["earnings"]
+
+	body := strings.Join([]string{
+		"Symbol\tCompany\tReport Time\tEstimate\tReported\t",
+		"AAPL\tApple Inc.\tAfter Close\t2.10\t2.18\t",
+		"MSFT\t\"Microsoft\tCorporation\"\tAfter Close\t2.78\t2.93\t",
+		"XOM\tExxon Mobil Corporation\tBefore Open\t2.20\t--\t\tx",
+		"KO\tCoca-Cola Company (The)\tBefore Open",
+		"NKE\tNIKE \"Inc\"\tAfter Close\t0.84\t1.03\t",
+		"A\"PL\tApple\t1\t2\t",
+		"",
+	}, "\r\n")
+
+	records, dropped, err := parseEarningReleaseBody([]byte(body), required)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"symbol", "company", "reportTime", "estimate", "reported"},
+		{"AAPL", "Apple Inc.", "After Close", "2.10", "2.18"},
+		{"MSFT", "Microsoft\tCorporation", "After Close", "2.78", "2.93"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("unexpected records: %q", records)
+	}
+
+	lines := []int{}
+	for _, d := range dropped {
+		if d.Reason == "" {
+			t.Fatalf("expected a reason for line %v", d.Line)
+		}
+		lines = append(lines, d.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{4, 5, 6, 7}) {
+		t.Fatalf("unexpected dropped rows: %+v", dropped)
+	}
+}
+
+func TestParseEarningReleaseBodyColumnOrder(t *testing.T) {
+	body := "Reported\tSymbol\tEstimate\tCompany\tPrice % Change\n2.18\tAAPL\t2.10\tApple Inc.\t1.20%\n"
+
+	records, dropped, err := parseEarningReleaseBody([]byte(body), requiredColumns["earnings"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dropped) != 0 {
+		t.Fatalf("unexpected dropped rows: %+v", dropped)
+	}
+
+	want := [][]string{
+		{"reported", "symbol", "estimate", "company", "pricePercentChange"},
+		{"2.18", "AAPL", "2.10", "Apple Inc.", "1.20%"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("unexpected records: %q", records)
+	}
+}
+
+func TestParseEarningReleaseBodyMissingColumn(t *testing.T) {
+	body := "Symbol\tCompany\tReport Time\tEstimate\t\nAAPL\tApple Inc.\tAfter Close\t2.10\t\n"
+
+	_, _, err := parseEarningReleaseBody([]byte(body), requiredColumns["earnings"])
+	if err == nil || !strings.Contains(err.Error(), `"Reported"`) {
+		t.Fatalf("expected a missing column error, got %v", err)
+	}
+
+	// Other tabs don't need estimates
+	_, _, err = parseEarningReleaseBody([]byte(body), requiredColumns["splits"])
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	cases := map[string]string{
+		"Symbol":         "symbol",
+		"Report Time":    "reportTime",
+		"Price % Change": "pricePercentChange",
+		"Market Cap (M)": "marketCapM",
+		"Ex-Div Date":    "exDivDate",
+		"":               "",
+	}
+
+	for header, want := range cases {
+		if got := columnName(header); got != want {
+			t.Fatalf("columnName(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
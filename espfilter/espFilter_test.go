@@ -1,36 +1,293 @@
 package espfilter
 
 import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/iamburbo/zacks-scraper/config"
-	"github.com/iamburbo/zacks-scraper/util"
+	"github.com/iamburbo/zacks-scraper/output"
 	"github.com/iamburbo/zacks-scraper/zacks"
+	"github.com/iamburbo/zacks-scraper/zackstest"
 )
 
 func TestRunEspFilter(t *testing.T) {
-	cfg, err := config.LoadConfigFile("../config.yml")
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &config.ScrapeJob{
+		JobType: "esp_filter",
+		OutDir:  t.TempDir(),
+		Parameters: []map[string]interface{}{
+			{"filter_type": "sells"},
+			{"esp_checkboxes": []interface{}{1}},
+		},
+	}
+
+	err = RunEspFilter(job, client, output.NewRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(job.OutDir, "*.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %v", len(files))
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) < 2 {
+		t.Fatalf("expected a header and rows, got %v records", len(records))
+	}
+
+	requests := server.RequestsTo("/esp/esp_buysell_data_handler.php")
+	if len(requests) != 1 || requests[0].Form.Get("hd_esp_type") != "2" {
+		t.Fatalf("unexpected esp requests: %+v", requests)
+	}
+}
+
+func TestRunEspFilterWhere(t *testing.T) {
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	client := util.GetTestClient()
+	job := &config.ScrapeJob{
+		JobType: "esp_filter",
+		OutDir:  t.TempDir(),
+		Compute: []config.ComputedColumn{
+			{Name: "estimate_gap", Expr: "`Most Accurate Estimate` - `Consensus Estimate`"},
+		},
+		Where: "ESP > 4 and estimate_gap > 0",
+		Parameters: []map[string]interface{}{
+			{"filter_type": "buys"},
+			{"esp_checkboxes": []interface{}{1}},
+			{"zacks_rank_checkboxes": []interface{}{1, 2, 3}},
+		},
+	}
+
+	err = RunEspFilter(job, client, output.NewRun())
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	err = zacks.LogIn(client, cfg)
+	files, err := filepath.Glob(filepath.Join(job.OutDir, "*.csv"))
 	if err != nil {
 		t.Fatal(err)
 	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %v", len(files))
+	}
 
-	var job *config.ScrapeJob = nil
-	for _, j := range cfg.Jobs {
-		if j.JobType == "esp_filter" {
-			job = &j
-			break
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) < 2 || records[0][len(records[0])-1] != "estimate_gap" {
+		t.Fatalf("expected rows with the computed column, got %v", records)
+	}
+	for _, r := range records[1:] {
+		if r[0] == "AAPL" {
+			t.Fatalf("expected AAPL to be filtered out, got %v", records)
 		}
 	}
+}
 
-	err = RunEspFilter(job, client)
+func TestRunEspFilterBoth(t *testing.T) {
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &config.ScrapeJob{
+		JobType: "esp_filter",
+		OutDir:  t.TempDir(),
+		Parameters: []map[string]interface{}{
+			{"filter_type": "both"},
+		},
+	}
+
+	err = RunEspFilter(job, client, output.NewRun())
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	files, err := filepath.Glob(filepath.Join(job.OutDir, "*.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %v", len(files))
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"side", "Symbol", "Company", "ESP", "Most Accurate Estimate", "Consensus Estimate", "Price", "Zacks Rank", "% Surprise (Last Qtr.)", "Reporting Date"}
+	if !reflect.DeepEqual(records[0], expected) {
+		t.Fatalf("expected header %v, got %v", expected, records[0])
+	}
+
+	sides := map[string]int{}
+	for _, r := range records[1:] {
+		sides[r[0]]++
+	}
+	if sides["buys"] != 2 || sides["sells"] != 1 {
+		t.Fatalf("expected 2 buys and 1 sell, got %v", records)
+	}
+
+	// Typed values
+	aapl := records[1]
+	if aapl[1] != "AAPL" || aapl[3] != "3.81" || aapl[7] != "3" || aapl[8] != "4.27" || !strings.HasSuffix(aapl[9], "-01-25") {
+		t.Fatalf("unexpected values: %v", aapl)
+	}
+	if intc := records[3]; intc[1] != "INTC" || intc[3] != "-4" {
+		t.Fatalf("unexpected values: %v", intc)
+	}
+
+	requests := server.RequestsTo("/esp/esp_buysell_data_handler.php")
+	if len(requests) != 2 || requests[0].Form.Get("hd_esp_type") != "1" || requests[1].Form.Get("hd_esp_type") != "2" {
+		t.Fatalf("unexpected esp requests: %+v", requests)
+	}
+}
+
+// Replaying both sides of a day with only the buys archived writes the buys
+func TestRunEspFilterBothPartiallyArchived(t *testing.T) {
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archiveDir := t.TempDir()
+	job := &config.ScrapeJob{
+		JobType:    "esp_filter",
+		OutDir:     t.TempDir(),
+		ArchiveRaw: true,
+		ArchiveDir: archiveDir,
+		Parameters: []map[string]interface{}{
+			{"filter_type": "buys"},
+		},
+	}
+	err = RunEspFilter(job, client, output.NewRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayed := &config.ScrapeJob{
+		JobType:    "esp_filter",
+		OutDir:     t.TempDir(),
+		ArchiveDir: archiveDir,
+		Parameters: []map[string]interface{}{
+			{"filter_type": "both"},
+		},
+	}
+	run := output.NewRun()
+	run.Replay = &output.Replay{Date: time.Now()}
+	err = RunEspFilter(replayed, nil, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(replayed.OutDir, "*.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %v", len(files))
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[1][0] != "buys" || records[2][0] != "buys" {
+		t.Fatalf("expected the 2 archived buys, got %v", records)
+	}
+}
+
+func TestParseReportingDate(t *testing.T) {
+	cases := []struct {
+		value     string
+		fetchedAt time.Time
+		expected  string
+	}{
+		{"1/25", time.Date(2024, 1, 22, 9, 0, 0, 0, time.UTC), "2024-01-25"},
+		{"1/5", time.Date(2023, 12, 28, 9, 0, 0, 0, time.UTC), "2024-01-05"},
+		{"12/29", time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC), "2023-12-29"},
+		{"2/9/2024", time.Date(2024, 1, 22, 9, 0, 0, 0, time.UTC), "2024-02-09"},
+		{"--", time.Date(2024, 1, 22, 9, 0, 0, 0, time.UTC), ""},
+	}
+
+	for _, c := range cases {
+		d, err := parseReportingDate(c.value, c.fetchedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := ""
+		if d != nil {
+			got = d.Format("2006-01-02")
+		}
+		if got != c.expected {
+			t.Errorf("%v fetched at %v: expected %v, got %v", c.value, c.fetchedAt, c.expected, got)
+		}
+	}
+
+	_, err := parseReportingDate("next week", time.Now())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
 }
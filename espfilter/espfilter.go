@@ -2,7 +2,6 @@ package espfilter
 
 import (
 	"bytes"
-	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,155 +9,193 @@ import (
 	"log"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/iamburbo/zacks-scraper/archive"
 	"github.com/iamburbo/zacks-scraper/config"
+	"github.com/iamburbo/zacks-scraper/output"
 	"github.com/iamburbo/zacks-scraper/util"
+	"github.com/iamburbo/zacks-scraper/zacks"
 )
 
 type EspFilterParameters struct {
-	FilterType             string
-	EspCheckboxes          []int
-	ZacksRankCheckboxes    []int
-	SurpCheckboxes         []int
-	ReportingDateChecboxes []int
+	FilterType string
+	Checked    []checkbox
 }
 
-func RunEspFilter(job *config.ScrapeJob, client *http.Client) error {
+// Values of the hd_esp_type field, by filter type
+var espTypes = map[string]string{
+	"buys":  "1",
+	"sells": "2",
+}
+
+// A row of the ESP filter. Values Zacks leaves out (--, NA) are nil
+type EspRow struct {
+	Side          string     `parquet:"name=side, type=BYTE_ARRAY, convertedtype=UTF8"` // buys or sells
+	Symbol        string     `parquet:"name=Symbol, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Company       string     `parquet:"name=Company, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ESP           *float64   `parquet:"name=ESP, type=DOUBLE"` // percent
+	MostAccurate  *float64   `parquet:"name=Most Accurate Estimate, type=DOUBLE"`
+	Consensus     *float64   `parquet:"name=Consensus Estimate, type=DOUBLE"`
+	Price         *float64   `parquet:"name=Price, type=DOUBLE"`
+	ZacksRank     *int64     `parquet:"name=Zacks Rank, type=INT64"`
+	Surprise      *float64   `parquet:"name=% Surprise (Last Qtr.), type=DOUBLE"` // percent
+	ReportingDate *time.Time `parquet:"name=Reporting Date, type=INT32, convertedtype=DATE"`
+}
+
+func RunEspFilter(job *config.ScrapeJob, client *http.Client, run *output.Run) error {
 	if job.JobType != "esp_filter" {
 		return fmt.Errorf("invalid job type: %v", job)
 	}
 
 	// Parse parameters
-	params := parseJobParameters(job.Parameters)
-
-	body, err := filterRequest(client, params)
+	params, err := parseJobParameters(job.Parameters)
 	if err != nil {
 		return err
 	}
 
-	data, err := convertBodyToCSV(body)
-	if err != nil {
-		return err
+	sides := []string{params.FilterType}
+	if params.FilterType == "both" {
+		sides = []string{"buys", "sells"}
 	}
 
-	// Write data to output directory
-	fileName := time.Now().Format("20060102150405") + ".csv"
-	f, err := os.Create(filepath.Join(job.OutDir, fileName))
-	if err != nil {
-		log.Fatalf("error creating output file: %v", err)
-	}
-	w := csv.NewWriter(f)
-	defer w.Flush()
+	rows := []*EspRow{}
+	var exchange *archive.Exchange
+	for _, side := range sides {
+		body, ex, err := run.Fetch(job, archive.Entry{
+			Kind: archive.KindEspJSON,
+			Tab:  side,
+		}, func() ([]byte, *archive.Exchange, error) {
+			return filterRequest(client, params, side)
+		})
+		// A replayed day may only have one side archived
+		if errors.Is(err, output.ErrNotArchived) {
+			log.Printf("skipping esp filter %v: %v", side, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
 
-	for _, record := range data {
-		if err = w.Write(record); err != nil {
-			log.Fatalln("error writing record to file", err)
+		sideRows, err := parseEspRows(body, side, ex.FetchedAt)
+		if err != nil {
+			return fmt.Errorf("error parsing esp %v: %w", side, err)
 		}
+		rows = append(rows, sideRows...)
+
+		if exchange == nil {
+			exchange = ex
+		}
+	}
+	if exchange == nil {
+		return nil
+	}
+
+	table, err := output.NewTable("esp_filter", rows)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	// Write data to output directory
+	return run.WriteTable(job, output.Partition{
+		Date:         exchange.FetchedAt,
+		Ext:          "csv",
+		FlatTemplate: "{timestamp}.{ext}",
+		Endpoint:     zacks.EspURL(),
+		ScrapedAt:    exchange.FetchedAt,
+		Rules: []output.Rule{
+			output.SymbolFormat("Symbol"),
+			output.UniqueSymbol("Symbol"),
+		},
+	}, table)
 }
 
-func parseJobParameters(parameters []map[string]interface{}) *EspFilterParameters {
-	var filterType string
-	espCheckboxes := []int{}
-	zacksRankCheckboxes := []int{}
-	surpCheckboxes := []int{}
-	reportingDateChecboxes := []int{}
+// Parses the filter type and the checked boxes, given by their position
+// in each group with the *_checkboxes parameters
+func parseJobParameters(parameters []map[string]interface{}) (*EspFilterParameters, error) {
+	params := &EspFilterParameters{}
+
 	for _, p := range parameters {
 		if t, ok := p["filter_type"]; ok {
-			filterType = t.(string)
-		} else if values, ok := p["esp_checkboxes"]; ok {
-			vArr := values.([]interface{})
-			for _, v := range vArr {
-				espCheckboxes = append(espCheckboxes, v.(int))
+			params.FilterType = fmt.Sprint(t)
+		}
+
+		for name, parameter := range namedOptions {
+			if _, ok := p[name]; ok {
+				return nil, fmt.Errorf("esp filter: %v isn't supported, check boxes by their position with %v", name, parameter)
 			}
-		} else if values, ok := p["zacks_rank_checkboxes"]; ok {
-			vArr := values.([]interface{})
-			for _, v := range vArr {
-				zacksRankCheckboxes = append(zacksRankCheckboxes, v.(int))
+		}
+
+		for _, f := range Filters {
+			values, ok := p[f.Parameter]
+			if !ok {
+				continue
 			}
-		} else if values, ok := p["surp_checkboxes"]; ok {
-			vArr := values.([]interface{})
-			for _, v := range vArr {
-				surpCheckboxes = append(surpCheckboxes, v.(int))
+			list, ok := values.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%v must be a list of checkbox numbers, got %v", f.Parameter, values)
 			}
-		} else if values, ok := p["reporting_date_checkboxes"]; ok {
-			vArr := values.([]interface{})
-			for _, v := range vArr {
-				reportingDateChecboxes = append(reportingDateChecboxes, v.(int))
+			for _, v := range list {
+				box, ok := v.(int)
+				if !ok || box < 1 {
+					return nil, fmt.Errorf("%v must be a list of checkbox numbers, got %v", f.Parameter, v)
+				}
+				params.Checked = append(params.Checked, checkbox{Group: f.Group, Box: box})
 			}
 		}
 	}
 
-	return &EspFilterParameters{
-		FilterType:             filterType,
-		EspCheckboxes:          espCheckboxes,
-		ZacksRankCheckboxes:    zacksRankCheckboxes,
-		SurpCheckboxes:         surpCheckboxes,
-		ReportingDateChecboxes: reportingDateChecboxes,
+	if _, ok := espTypes[params.FilterType]; !ok && params.FilterType != "both" {
+		return nil, fmt.Errorf("esp filter: unknown filterType %v, expected buys, sells or both", params.FilterType)
 	}
+
+	return params, nil
 }
 
-func writeFilterQuery(parameters *EspFilterParameters) (string, error) {
+// Writes the filter form for one side, buys or sells
+func writeFilterQuery(parameters *EspFilterParameters, side string) (string, error) {
 	w := url.Values{}
 
-	for _, i := range parameters.EspCheckboxes {
-		w.Add("filter_checklist[]", "1#"+strconv.Itoa(i))
-	}
-
-	for _, i := range parameters.ZacksRankCheckboxes {
-		w.Add("filter_checklist[]", "2#"+strconv.Itoa(i))
-	}
-
-	for _, i := range parameters.SurpCheckboxes {
-		w.Add("filter_checklist[]", "3#"+strconv.Itoa(i))
-	}
-
-	for _, i := range parameters.ReportingDateChecboxes {
-		w.Add("filter_checklist[]", "5#"+strconv.Itoa(i))
+	for _, c := range parameters.Checked {
+		w.Add("filter_checklist[]", strconv.Itoa(c.Group)+"#"+strconv.Itoa(c.Box))
 	}
 
-	if parameters.FilterType == "buys" {
-		w.Set("hd_esp_type", "1")
-	} else if parameters.FilterType == "sells" {
-		w.Set("hd_esp_type", "2")
-	} else {
-		return "", fmt.Errorf("esp filter: unknown filterType %v", parameters.FilterType)
+	espType, ok := espTypes[side]
+	if !ok {
+		return "", fmt.Errorf("esp filter: unknown filterType %v", side)
 	}
+	w.Set("hd_esp_type", espType)
 
 	return w.Encode(), nil
 }
 
-func filterRequest(client *http.Client, parameters *EspFilterParameters) ([]byte, error) {
+func filterRequest(client *http.Client, parameters *EspFilterParameters, side string) ([]byte, *archive.Exchange, error) {
 
-	filterUrl, err := url.Parse("https://www.zacks.com/esp/esp_buysell_data_handler.php")
+	filterUrl, err := url.Parse(zacks.EspURL())
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Construct filter queries
 	buf := new(bytes.Buffer)
-	body, err := writeFilterQuery(parameters)
+	body, err := writeFilterQuery(parameters, side)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	buf.Write([]byte(body))
 
 	req, err := http.NewRequest("POST", filterUrl.String(), buf)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	req.Header.Set("user-agent", `Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/106.0.0.0 Safari/537.36`)
 	req.Header.Set("accept", "application/json, text/javascript, */*; q=0.01")
 	req.Header.Set("content-type", `application/x-www-form-urlencoded; charset=UTF-8;`)
 
-	cookieUrl, _ := url.Parse("https://www.zacks.com")
+	cookieUrl, _ := url.Parse(zacks.WWW)
 	for _, c := range client.Jar.Cookies(cookieUrl) {
 		req.AddCookie(c)
 	}
@@ -167,30 +204,29 @@ func filterRequest(client *http.Client, parameters *EspFilterParameters) ([]byte
 		Value: "edit_criteria",
 	})
 
+	fetchedAt := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	switch resp.StatusCode {
 	case 200:
-		return bodyBytes, nil
+		return bodyBytes, archive.NewExchange(req, []byte(body), resp, fetchedAt), nil
 	default:
-		return nil, errors.New("status code: " + strconv.Itoa(resp.StatusCode))
+		return nil, nil, errors.New("status code: " + strconv.Itoa(resp.StatusCode))
 	}
 }
 
-func convertBodyToCSV(body []byte) ([][]string, error) {
-	// Header
-	csvArray := [][]string{}
-	csvArray = append(csvArray, []string{"Symbol", "Company", "ESP", "Most Accurate Estimate", "Consensus Estimate", "Price", "Zacks Rank", "% Surprise (Last Qtr.)", "Reporting Date"})
-
+// Parses the rows of one side. fetchedAt dates the reporting dates, which
+// Zacks shows without a year
+func parseEspRows(body []byte, side string, fetchedAt time.Time) ([]*EspRow, error) {
 	type espFilterResponse struct {
 		Data [][]string `json:"data"`
 	}
@@ -201,16 +237,88 @@ func convertBodyToCSV(body []byte) ([][]string, error) {
 		return nil, err
 	}
 
-	for _, d := range data.Data {
+	rows := []*EspRow{}
+	for i, d := range data.Data {
+		if len(d) < 9 {
+			return nil, fmt.Errorf("row %v has %v cells, expected 9", i, len(d))
+		}
+
+		row := &EspRow{
+			Side:    side,
+			Symbol:  util.CellClassText(d[0], "hoverquote-symbol"),
+			Company: util.CellText(d[1]),
+		}
+
+		numbers := []struct {
+			column string
+			cell   string
+			value  **float64
+		}{
+			{"ESP", util.CellText(d[2]), &row.ESP},
+			{"Most Accurate Estimate", util.CellText(d[3]), &row.MostAccurate},
+			{"Consensus Estimate", util.CellText(d[4]), &row.Consensus},
+			{"Price", util.CellText(d[5]), &row.Price},
+			{"% Surprise (Last Qtr.)", util.CellText(d[7]), &row.Surprise},
+		}
+		for _, n := range numbers {
+			*n.value, err = output.ParseNumber(n.cell)
+			if err != nil {
+				return nil, fmt.Errorf("row %v: invalid %v: %w", i, n.column, err)
+			}
+		}
 
-		symbol := util.FindStringInBetween(`<span class="hoverquote-symbol">`, `<span class="sr-only">`, d[0])
-		companyName := util.FindStringInBetween(`>`, `</a>`, d[1])
-		esp := util.FindStringInBetween(`>`, `</span>`, d[2])
-		zacksRank := util.FindStringInBetween(`>`, `</span>`, d[6])
-		percentSurprise := util.FindStringInBetween(`>`, `</span>`, d[7])
+		rank, err := output.ParseNumber(util.CellText(d[6]))
+		if err != nil {
+			return nil, fmt.Errorf("row %v: invalid Zacks Rank: %w", i, err)
+		}
+		if rank != nil {
+			r := int64(*rank)
+			row.ZacksRank = &r
+		}
 
-		csvArray = append(csvArray, []string{symbol, companyName, esp, d[3], d[4], d[5], zacksRank, percentSurprise, d[8]})
+		row.ReportingDate, err = parseReportingDate(util.CellText(d[8]), fetchedAt)
+		if err != nil {
+			return nil, fmt.Errorf("row %v: invalid Reporting Date: %w", i, err)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// Parses a reporting date such as 1/25. Zacks leaves out the year, so the
+// date is placed in the year closest to fetchedAt: a list fetched in late
+// December already shows January reports
+func parseReportingDate(v string, fetchedAt time.Time) (*time.Time, error) {
+	v = strings.TrimSpace(v)
+	if v == "" || v == "--" || v == "NA" {
+		return nil, nil
+	}
+
+	if d, err := time.Parse("1/2/2006", v); err == nil {
+		return &d, nil
 	}
 
-	return csvArray, nil
+	d, err := time.Parse("1/2", v)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a date", v)
+	}
+
+	fetched := time.Date(fetchedAt.Year(), fetchedAt.Month(), fetchedAt.Day(), 0, 0, 0, 0, time.UTC)
+	var closest time.Time
+	for _, year := range []int{fetched.Year() - 1, fetched.Year(), fetched.Year() + 1} {
+		c := time.Date(year, d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+		if closest.IsZero() || absDuration(c.Sub(fetched)) < absDuration(closest.Sub(fetched)) {
+			closest = c
+		}
+	}
+	return &closest, nil
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
 }
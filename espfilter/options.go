@@ -0,0 +1,53 @@
+package espfilter
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// A group of checkboxes of the ESP filter. Checked boxes are sent as
+// filter_checklist[] values of the form <group>#<box>, where box is the
+// position of the checkbox in its group, from 1.
+//
+// What each box of a group stands for hasn't been recorded from the ESP
+// page, so boxes are only given by position. Named options need a
+// recording of the page's checkbox markup in zackstest/fixtures first
+type Filter struct {
+	Parameter string // job parameter listing the checked boxes
+	Group     int
+}
+
+// Filter groups of the ESP page, as sent by the browser
+var Filters = []Filter{
+	{"esp_checkboxes", 1},
+	{"zacks_rank_checkboxes", 2},
+	{"surp_checkboxes", 3},
+	{"reporting_date_checkboxes", 5},
+}
+
+// A checked box of the ESP filter
+type checkbox struct {
+	Group int
+	Box   int
+}
+
+// Parameters naming options, which can't be checked against the page
+var namedOptions = map[string]string{
+	"esp":        "esp_checkboxes",
+	"zacks_rank": "zacks_rank_checkboxes",
+	"surprise":   "surp_checkboxes",
+	"reporting":  "reporting_date_checkboxes",
+}
+
+// Writes the filter groups with their job parameter and group code
+func WriteOptions(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "parameter\tsent as")
+	for _, f := range Filters {
+		fmt.Fprintf(tw, "%v\t%v#<box>\n", f.Parameter, f.Group)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "<box> is the position of a checkbox in its group on the ESP page, from 1.")
+	return tw.Flush()
+}
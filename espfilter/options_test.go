@@ -0,0 +1,69 @@
+package espfilter
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseJobParameters(t *testing.T) {
+	params, err := parseJobParameters([]map[string]interface{}{
+		{"filter_type": "buys"},
+		{"esp_checkboxes": []interface{}{1, 3}},
+		{"zacks_rank_checkboxes": []interface{}{1, 2}},
+		{"reporting_date_checkboxes": []interface{}{2}},
+		{"surp_checkboxes": []interface{}{1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := writeFilterQuery(params, params.FilterType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := url.ParseQuery(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"1#1", "1#3", "2#1", "2#2", "5#2", "3#1"}
+	if !reflect.DeepEqual(q["filter_checklist[]"], expected) {
+		t.Fatalf("expected %v, got %v", expected, q["filter_checklist[]"])
+	}
+	if q.Get("hd_esp_type") != "1" {
+		t.Fatalf("expected buys, got %v", q.Get("hd_esp_type"))
+	}
+}
+
+func TestParseJobParametersInvalid(t *testing.T) {
+	cases := map[string]map[string]interface{}{
+		"named option":       {"esp": []interface{}{"> 0%"}},
+		"checkbox zero":      {"esp_checkboxes": []interface{}{0}},
+		"checkbox not a int": {"esp_checkboxes": []interface{}{"> 0%"}},
+		"checkbox not list":  {"zacks_rank_checkboxes": 1},
+		"unknown side":       {"filter_type": "holds"},
+	}
+
+	for name, p := range cases {
+		_, err := parseJobParameters([]map[string]interface{}{{"filter_type": "buys"}, p})
+		if err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
+
+func TestWriteOptions(t *testing.T) {
+	b := new(strings.Builder)
+	err := WriteOptions(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range Filters {
+		if !strings.Contains(b.String(), f.Parameter) {
+			t.Fatalf("expected %q in the options, got:\n%v", f.Parameter, b)
+		}
+	}
+}
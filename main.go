@@ -1,21 +1,34 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"net/http/cookiejar"
+	"os"
 	"time"
 
 	"github.com/iamburbo/zacks-scraper/config"
 	"github.com/iamburbo/zacks-scraper/earningscalendar"
 	"github.com/iamburbo/zacks-scraper/earningsrelease"
 	"github.com/iamburbo/zacks-scraper/espfilter"
+	"github.com/iamburbo/zacks-scraper/output"
 	"github.com/iamburbo/zacks-scraper/stockscreener"
+	"github.com/iamburbo/zacks-scraper/symbolearnings"
 	"github.com/iamburbo/zacks-scraper/zacks"
 	"golang.org/x/net/publicsuffix"
 )
 
 func main() {
+	// Commands that don't need a config
+	if config.ParseCommandFromArgs() == "esp-options" {
+		err := espfilter.WriteOptions(os.Stdout)
+		if err != nil {
+			log.Fatalf("Error listing esp options: %v", err)
+		}
+		return
+	}
+
 	// Load config
 	configPath, err := config.ParseConfigPathFromArgs()
 	if err != nil {
@@ -27,40 +40,56 @@ func main() {
 		log.Fatalf("Error loading config file: %v", err)
 	}
 
-	// Setup http client
-	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	err = zacks.Configure(cfg.BaseURLs)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("Error configuring Zacks base URLs: %v", err)
 	}
-	client := &http.Client{
-		Jar: jar,
+
+	switch command := config.ParseCommandFromArgs(); command {
+	case "":
+	case "replay":
+		opts, err := parseReplayOptionsFromArgs()
+		if err != nil {
+			log.Fatalf("Error parsing command line args: %v", err)
+		}
+
+		err = replay(cfg, opts)
+		if err != nil {
+			log.Fatalf("Error during replay: %v", err)
+		}
+		return
+	default:
+		log.Fatalf("Unknown command: %v", command)
 	}
 
-	// Retreive logged in session
-	err = zacks.LogIn(client, cfg)
+	client, err := logIn(cfg)
 	if err != nil {
 		log.Fatalf("Error while logging in: %v", err)
 	}
 
+	run := output.NewRun()
+
 	// Execute each job from the config, retrying if necessary
 	for _, job := range cfg.Jobs {
 		retry := 0
 		for retry < cfg.MaxRetries {
-			var err error
-			err = nil
-			switch job.JobType {
-			case "stock_screener":
-				err = stockscreener.RunStockScreener(&job, client)
-			case "esp_filter":
-				err = espfilter.RunEspFilter(&job, client)
-			case "earnings_release":
-				err = earningsrelease.RunEarningsRelease(&job, client)
-			case "earnings_calendar":
-				err = earningscalendar.RunEarningsCalendar(&job, client)
+			err := runJob(&job, client, run)
+
+			// Retrying won't help until the parser is updated, or while
+			// Zacks keeps serving data that fails the job's rules
+			var drift *earningscalendar.SchemaDriftError
+			var invalid *output.ValidationError
+			if errors.As(err, &drift) || errors.As(err, &invalid) {
+				log.Printf("Error running %v job: %v", job.DisplayName(), err)
+				break
 			}
 
 			if err != nil {
 				retry++
+				if retry == cfg.MaxRetries {
+					log.Printf("Error running %v job after %v attempts: %v", job.DisplayName(), retry, err)
+					break
+				}
 				time.Sleep(time.Duration(cfg.DelayBetweenRetries) * time.Millisecond)
 			} else {
 				break
@@ -68,4 +97,45 @@ func main() {
 		}
 
 	}
+
+	err = run.WriteManifests()
+	if err != nil {
+		log.Fatalf("Error writing run manifests: %v", err)
+	}
+}
+
+// Sets up an http client with a logged in session
+func logIn(cfg *config.Config) (*http.Client, error) {
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Jar: jar,
+	}
+
+	err = zacks.LogIn(client, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// Runs a single job once
+func runJob(job *config.ScrapeJob, client *http.Client, run *output.Run) error {
+	switch job.JobType {
+	case "stock_screener":
+		return stockscreener.RunStockScreener(job, client, run)
+	case "esp_filter":
+		return espfilter.RunEspFilter(job, client, run)
+	case "earnings_release":
+		return earningsrelease.RunEarningsRelease(job, client, run)
+	case "earnings_calendar":
+		return earningscalendar.RunEarningsCalendar(job, client, run)
+	case "symbol_earnings":
+		return symbolearnings.RunSymbolEarnings(job, client, run)
+	}
+
+	return nil
 }
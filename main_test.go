@@ -3,9 +3,8 @@ package main
 import (
 	"testing"
 
-	"github.com/iamburbo/zacks-scraper/config"
-	"github.com/iamburbo/zacks-scraper/util"
 	"github.com/iamburbo/zacks-scraper/zacks"
+	"github.com/iamburbo/zacks-scraper/zackstest"
 )
 
 func TestReadInputJson(t *testing.T) {
@@ -15,25 +14,28 @@ func TestReadInputJson(t *testing.T) {
 	}
 }
 
-func TestFindStringInBetween(t *testing.T) {
-	left := "aba"
-	right := "bcb"
-	str := "test"
-	match := util.FindStringInBetween(left, right, left+str+right)
-	if match != str {
-		t.Fail()
-	}
-}
-
 func TestLogin(t *testing.T) {
-	client := util.GetTestClient()
-	config, err := config.LoadConfigFile("config.yml")
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	config := server.Config()
+
+	err := zacks.LogIn(client, config)
 	if err != nil {
 		t.Fatal(err)
 	}
+}
 
-	err = zacks.LogIn(client, config)
-	if err != nil {
-		t.Fatal(err)
+func TestLoginInvalidCredentials(t *testing.T) {
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	config := server.Config()
+	config.Password = "wrong"
+
+	err := zacks.LogIn(server.Client(), config)
+	if err == nil {
+		t.Fatal("expected login to fail")
 	}
 }
@@ -0,0 +1,19 @@
+package output
+
+import (
+	"path/filepath"
+
+	"github.com/iamburbo/zacks-scraper/config"
+)
+
+// Saves a payload that couldn't be parsed to <outDir>/_drift/<runId>/<name>
+// for diagnosis. Saved payloads aren't listed in the manifest. Returns the
+// path written
+func (r *Run) SaveDiagnostic(job *config.ScrapeJob, name string, body []byte) (string, error) {
+	path := filepath.Join(job.OutDir, "_drift", r.ID, name)
+	err := writeFileAtomic(path, body)
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,766 @@
+package output
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// An expression evaluated on the rows of a table, e.g.
+//
+//	ESP > 5 and days_until(`Reporting Date`) <= 3
+//
+// Expressions compare and combine typed values: numbers, strings, dates,
+// booleans and null. Text cells are read as numbers or dates when compared
+// with one, so "+3.81%" > 3 and "1/22/2024" < today() both hold. Cells
+// Zacks marks as missing (empty, -- or NA) are null, and comparisons with
+// null are neither true nor false
+type Expr struct {
+	src     string
+	root    node
+	columns []string // referenced by the expression
+}
+
+// Values of a row by column name
+type Env func(column string) (interface{}, bool)
+
+type node interface {
+	eval(env Env) (interface{}, error)
+}
+
+// Used by today(), replaced in tests
+var now = time.Now
+
+func ParseExpr(src string) (*Expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", src, err)
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.or()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %v", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", src, err)
+	}
+
+	return &Expr{src: src, root: root, columns: p.columns}, nil
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Columns the expression reads
+func (e *Expr) Columns() []string {
+	return e.columns
+}
+
+func (e *Expr) Eval(env Env) (interface{}, error) {
+	v, err := e.root.eval(env)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", e.src, err)
+	}
+	return v, nil
+}
+
+// Tokens
+
+type tokenKind int
+
+const (
+	tokenNumber tokenKind = iota
+	tokenString
+	tokenIdent  // column, function or keyword
+	tokenColumn // `quoted column`
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(src string) ([]token, error) {
+	tokens := []token{}
+	rs := []rune(src)
+
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(rs) && unicode.IsDigit(rs[i+1])):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{tokenNumber, string(rs[i:j])})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_' || rs[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{tokenIdent, string(rs[i:j])})
+			i = j
+		case r == '\'' || r == '"' || r == '`':
+			j := i + 1
+			for j < len(rs) && rs[j] != r {
+				j++
+			}
+			if j == len(rs) {
+				return nil, fmt.Errorf("unterminated %c", r)
+			}
+			kind := tokenString
+			if r == '`' {
+				kind = tokenColumn
+			}
+			tokens = append(tokens, token{kind, string(rs[i+1 : j])})
+			i = j + 1
+		default:
+			op := ""
+			if i+1 < len(rs) {
+				switch two := string(rs[i : i+2]); two {
+				case ">=", "<=", "!=", "<>", "==":
+					op = two
+				}
+			}
+			if op == "" {
+				if !strings.ContainsRune("()+-*/<>=,", r) {
+					return nil, fmt.Errorf("unexpected %c", r)
+				}
+				op = string(r)
+			}
+			tokens = append(tokens, token{tokenOperator, op})
+			i += len([]rune(op))
+		}
+	}
+
+	return tokens, nil
+}
+
+// Parser, from the lowest precedence up:
+//
+//	or:      and {"or" and}
+//	and:     not {"and" not}
+//	not:     "not" not | compare
+//	compare: sum [("=" | "!=" | "<" | "<=" | ">" | ">=") sum]
+//	sum:     product {("+" | "-") product}
+//	product: unary {("*" | "/") unary}
+//	unary:   "-" unary | primary
+//	primary: number | string | column | function "(" [or {"," or}] ")" | "(" or ")"
+
+type parser struct {
+	tokens  []token
+	pos     int
+	columns []string
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// Consumes the next token if it is the operator or keyword
+func (p *parser) accept(text string) bool {
+	t, ok := p.peek()
+	if !ok || t.kind == tokenString || t.kind == tokenColumn || !strings.EqualFold(t.text, text) {
+		return false
+	}
+	p.pos++
+	return true
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	for err == nil && p.accept("or") {
+		var right node
+		right, err = p.and()
+		left = &logical{"or", left, right}
+	}
+	return left, err
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.not()
+	for err == nil && p.accept("and") {
+		var right node
+		right, err = p.not()
+		left = &logical{"and", left, right}
+	}
+	return left, err
+}
+
+func (p *parser) not() (node, error) {
+	if p.accept("not") {
+		operand, err := p.not()
+		return &negation{operand}, err
+	}
+	return p.compare()
+}
+
+func (p *parser) compare() (node, error) {
+	left, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range []string{"<=", ">=", "!=", "<>", "==", "=", "<", ">"} {
+		if p.accept(op) {
+			right, err := p.sum()
+			return &comparison{op, left, right}, err
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) sum() (node, error) {
+	left, err := p.product()
+	for err == nil {
+		op := ""
+		switch {
+		case p.accept("+"):
+			op = "+"
+		case p.accept("-"):
+			op = "-"
+		default:
+			return left, nil
+		}
+		var right node
+		right, err = p.product()
+		left = &arithmetic{op, left, right}
+	}
+	return left, err
+}
+
+func (p *parser) product() (node, error) {
+	left, err := p.unary()
+	for err == nil {
+		op := ""
+		switch {
+		case p.accept("*"):
+			op = "*"
+		case p.accept("/"):
+			op = "/"
+		default:
+			return left, nil
+		}
+		var right node
+		right, err = p.unary()
+		left = &arithmetic{op, left, right}
+	}
+	return left, err
+}
+
+func (p *parser) unary() (node, error) {
+	if p.accept("-") {
+		operand, err := p.unary()
+		return &arithmetic{"-", literal{0.0}, operand}, err
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+
+	switch t.kind {
+	case tokenNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %v", t.text)
+		}
+		return literal{f}, nil
+	case tokenString:
+		return literal{t.text}, nil
+	case tokenColumn:
+		p.columns = append(p.columns, t.text)
+		return column(t.text), nil
+	case tokenOperator:
+		if t.text != "(" {
+			return nil, fmt.Errorf("unexpected %v", t.text)
+		}
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return inner, nil
+	}
+
+	switch strings.ToLower(t.text) {
+	case "true":
+		return literal{true}, nil
+	case "false":
+		return literal{false}, nil
+	case "null":
+		return literal{nil}, nil
+	case "and", "or", "not":
+		return nil, fmt.Errorf("unexpected %v", t.text)
+	}
+
+	if !p.accept("(") {
+		p.columns = append(p.columns, t.text)
+		return column(t.text), nil
+	}
+
+	name := strings.ToLower(t.text)
+	f, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %v", t.text)
+	}
+
+	args := []node{}
+	if !p.accept(")") {
+		for {
+			arg, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.accept(")") {
+				break
+			}
+			if !p.accept(",") {
+				return nil, fmt.Errorf("missing ) after the arguments of %v", name)
+			}
+		}
+	}
+	if len(args) < f.minArgs || (f.maxArgs >= 0 && len(args) > f.maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments for %v", name)
+	}
+	return &call{name, f, args}, nil
+}
+
+// Nodes
+
+type literal struct {
+	value interface{}
+}
+
+func (l literal) eval(env Env) (interface{}, error) {
+	return l.value, nil
+}
+
+type column string
+
+func (c column) eval(env Env) (interface{}, error) {
+	v, ok := env(string(c))
+	if !ok {
+		return nil, fmt.Errorf("unknown column %v", string(c))
+	}
+	return normalize(v), nil
+}
+
+type logical struct {
+	op          string
+	left, right node
+}
+
+func (l *logical) eval(env Env) (interface{}, error) {
+	a, err := l.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, err := l.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	x, xok := a.(bool)
+	y, yok := b.(bool)
+	if l.op == "and" {
+		if (xok && !x) || (yok && !y) {
+			return false, nil
+		}
+		if xok && yok {
+			return true, nil
+		}
+		return nil, nil
+	}
+
+	if (xok && x) || (yok && y) {
+		return true, nil
+	}
+	if xok && yok {
+		return false, nil
+	}
+	return nil, nil
+}
+
+type negation struct {
+	operand node
+}
+
+func (n *negation) eval(env Env) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if b, ok := v.(bool); ok {
+		return !b, nil
+	}
+	return nil, nil
+}
+
+type comparison struct {
+	op          string
+	left, right node
+}
+
+func (c *comparison) eval(env Env) (interface{}, error) {
+	a, err := c.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, err := c.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only null equals null
+	if a == nil || b == nil {
+		switch c.op {
+		case "=", "==":
+			return a == nil && b == nil, nil
+		case "!=", "<>":
+			return (a == nil) != (b == nil), nil
+		}
+		return nil, nil
+	}
+
+	n, ok := compareValues(a, b)
+	if !ok {
+		return nil, nil
+	}
+
+	switch c.op {
+	case "=", "==":
+		return n == 0, nil
+	case "!=", "<>":
+		return n != 0, nil
+	case "<":
+		return n < 0, nil
+	case "<=":
+		return n <= 0, nil
+	case ">":
+		return n > 0, nil
+	default:
+		return n >= 0, nil
+	}
+}
+
+type arithmetic struct {
+	op          string
+	left, right node
+}
+
+func (a *arithmetic) eval(env Env) (interface{}, error) {
+	x, err := a.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	y, err := a.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if x == nil || y == nil {
+		return nil, nil
+	}
+
+	// Dates move by days, and differ by a number of days
+	if d, ok := x.(time.Time); ok {
+		if e, ok := toDate(y); ok && a.op == "-" {
+			if _, isNumber := toNumber(y); !isNumber {
+				return d.Sub(e).Hours() / 24, nil
+			}
+		}
+		days, ok := toNumber(y)
+		if !ok {
+			return nil, nil
+		}
+		switch a.op {
+		case "+":
+			return d.AddDate(0, 0, int(days)), nil
+		case "-":
+			return d.AddDate(0, 0, -int(days)), nil
+		}
+		return nil, nil
+	}
+	if _, ok := y.(time.Time); ok && a.op == "-" {
+		if d, ok := toDate(x); ok {
+			return d.Sub(y.(time.Time)).Hours() / 24, nil
+		}
+		return nil, nil
+	}
+
+	m, mok := toNumber(x)
+	n, nok := toNumber(y)
+	if !mok || !nok {
+		// Text only concatenates
+		s, sok := x.(string)
+		t, tok := y.(string)
+		if a.op == "+" && sok && tok {
+			return s + t, nil
+		}
+		return nil, nil
+	}
+
+	switch a.op {
+	case "+":
+		return m + n, nil
+	case "-":
+		return m - n, nil
+	case "*":
+		return m * n, nil
+	default:
+		if n == 0 {
+			return nil, nil
+		}
+		return m / n, nil
+	}
+}
+
+type call struct {
+	name string
+	f    function
+	args []node
+}
+
+func (c *call) eval(env Env) (interface{}, error) {
+	args := make([]interface{}, len(c.args))
+	for i, arg := range c.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return c.f.call(args), nil
+}
+
+// Functions
+
+type function struct {
+	minArgs, maxArgs int // maxArgs is -1 for any number
+	call             func(args []interface{}) interface{}
+}
+
+var functions = map[string]function{
+	// Today's date
+	"today": {0, 0, func(args []interface{}) interface{} {
+		return day(now())
+	}},
+	// Reads a value as a number, or null
+	"number": {1, 1, func(args []interface{}) interface{} {
+		if n, ok := toNumber(args[0]); ok {
+			return n
+		}
+		return nil
+	}},
+	// Reads a value as a date, or null
+	"date": {1, 1, func(args []interface{}) interface{} {
+		if d, ok := toDate(args[0]); ok {
+			return d
+		}
+		return nil
+	}},
+	// Days from today to a date, negative for dates in the past
+	"days_until": {1, 1, func(args []interface{}) interface{} {
+		d, ok := toDate(args[0])
+		if !ok {
+			return nil
+		}
+		return math.Round(d.Sub(day(now())).Hours() / 24)
+	}},
+	"abs": {1, 1, func(args []interface{}) interface{} {
+		if n, ok := toNumber(args[0]); ok {
+			return math.Abs(n)
+		}
+		return nil
+	}},
+	// Rounds a number to a number of decimals, 0 by default
+	"round": {1, 2, func(args []interface{}) interface{} {
+		n, ok := toNumber(args[0])
+		if !ok {
+			return nil
+		}
+		decimals := 0.0
+		if len(args) == 2 {
+			if decimals, ok = toNumber(args[1]); !ok {
+				return nil
+			}
+		}
+		scale := math.Pow(10, decimals)
+		return math.Round(n*scale) / scale
+	}},
+	"lower": {1, 1, func(args []interface{}) interface{} {
+		if args[0] == nil {
+			return nil
+		}
+		return strings.ToLower(toString(args[0]))
+	}},
+	"upper": {1, 1, func(args []interface{}) interface{} {
+		if args[0] == nil {
+			return nil
+		}
+		return strings.ToUpper(toString(args[0]))
+	}},
+	"contains": {2, 2, func(args []interface{}) interface{} {
+		if args[0] == nil || args[1] == nil {
+			return nil
+		}
+		return strings.Contains(toString(args[0]), toString(args[1]))
+	}},
+	// The first argument that isn't null
+	"coalesce": {1, -1, func(args []interface{}) interface{} {
+		for _, a := range args {
+			if a != nil {
+				return a
+			}
+		}
+		return nil
+	}},
+}
+
+// Values
+
+// Converts a table value to one of the expression types: nil, float64,
+// string, bool or time.Time
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case string:
+		if isMissing(strings.TrimSpace(v)) {
+			return nil
+		}
+		return v
+	}
+	return v
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := ParseNumber(v)
+		if err != nil || f == nil {
+			return 0, false
+		}
+		return *f, true
+	}
+	return 0, false
+}
+
+// Date layouts found in Zacks tables
+var dateLayouts = []string{"2006-01-02", "1/2/2006", "1/2/06"}
+
+func toDate(v interface{}) (time.Time, bool) {
+	switch v := v.(type) {
+	case time.Time:
+		return day(v), true
+	case string:
+		v = strings.TrimSpace(v)
+		for _, layout := range dateLayouts {
+			if d, err := time.Parse(layout, v); err == nil {
+				return d, true
+			}
+		}
+		// Month and day only, e.g. the ESP reporting date. The year is the
+		// one putting the date closest to today
+		if d, err := time.Parse("1/2", v); err == nil {
+			today := day(now())
+			best := time.Time{}
+			for _, year := range []int{today.Year() - 1, today.Year(), today.Year() + 1} {
+				c := time.Date(year, d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+				if best.IsZero() || math.Abs(c.Sub(today).Hours()) < math.Abs(best.Sub(today).Hours()) {
+					best = c
+				}
+			}
+			return best, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format("2006-01-02")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Orders two values that aren't null. A date or number on either side
+// reads the other as one too; two texts compare as numbers when both are.
+// ok is false when the values can't be compared
+func compareValues(a, b interface{}) (n int, ok bool) {
+	_, aDate := a.(time.Time)
+	_, bDate := b.(time.Time)
+	if aDate || bDate {
+		x, xok := toDate(a)
+		y, yok := toDate(b)
+		if !xok || !yok {
+			return 0, false
+		}
+		switch {
+		case x.Before(y):
+			return -1, true
+		case x.After(y):
+			return 1, true
+		}
+		return 0, true
+	}
+
+	x, xok := toNumber(a)
+	y, yok := toNumber(b)
+	if xok && yok {
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	_, aNumber := a.(float64)
+	_, bNumber := b.(float64)
+	if aNumber || bNumber {
+		return 0, false
+	}
+
+	p, pok := a.(bool)
+	q, qok := b.(bool)
+	if pok || qok {
+		if !pok || !qok || p == q {
+			return 0, pok && qok
+		}
+		if p {
+			return 1, true
+		}
+		return -1, true
+	}
+
+	return strings.Compare(toString(a), toString(b)), true
+}
@@ -0,0 +1,88 @@
+package output
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestExpr(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 1, 22, 15, 0, 0, 0, time.Local) }
+	defer func() { now = time.Now }()
+
+	row := map[string]interface{}{
+		"ESP":            "+3.81%",
+		"Reporting Date": "1/25",
+		"estimate":       "2.10",
+		"reported":       "2.18",
+		"exDivDate":      "1/22/2024",
+		"surprise":       "--",
+		"shares":         int64(1500),
+		"symbol":         "AAPL",
+	}
+	env := func(column string) (interface{}, bool) {
+		v, ok := row[column]
+		return v, ok
+	}
+
+	cases := map[string]interface{}{
+		"ESP > 3": true,
+		"ESP > 5 and days_until(`Reporting Date`) <= 3":    false,
+		"ESP > 3 and days_until(`Reporting Date`) <= 3":    true,
+		"days_until(`Reporting Date`)":                     3.0,
+		"round((reported - estimate) / estimate * 100, 2)": 3.81,
+		"date(exDivDate) = today()":                        true,
+		"exDivDate < today() + 1":                          true,
+		"date('2024-02-01') - date(exDivDate)":             10.0,
+		"shares / 1000":                                    1.5,
+		"-shares + 1":                                      -1499.0,
+		"surprise > 10":                                    nil,
+		"surprise = null":                                  true,
+		"surprise > 10 or symbol = 'AAPL'":                 true,
+		"not (surprise > 10)":                              nil,
+		"coalesce(surprise, 0)":                            0.0,
+		"lower(symbol) + '.us'":                            "aapl.us",
+		"contains(symbol, \"AP\") and not false":           true,
+		"estimate <= 2.1":                                  true,
+		"symbol <> 'MSFT'":                                 true,
+		"abs(estimate - reported) * 100":                   8.0,
+	}
+
+	for src, expected := range cases {
+		expr, err := ParseExpr(src)
+		if err != nil {
+			t.Errorf("%v", err)
+			continue
+		}
+		v, err := expr.Eval(env)
+		if err != nil {
+			t.Errorf("%v", err)
+			continue
+		}
+		if f, ok := v.(float64); ok {
+			v = math.Round(f*1e6) / 1e6
+		}
+		if v != expected {
+			t.Errorf("%v: expected %v, got %v", src, expected, v)
+		}
+	}
+}
+
+func TestParseExprInvalid(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"ESP >",
+		"(ESP > 3",
+		"ESP > 3)",
+		"ESP ! 3",
+		"'unterminated",
+		"unknown_function(ESP)",
+		"round()",
+		"ESP > 3 and",
+	} {
+		_, err := ParseExpr(src)
+		if err == nil {
+			t.Errorf("expected an error for %q", src)
+		}
+	}
+}
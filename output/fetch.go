@@ -0,0 +1,104 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/archive"
+	"github.com/iamburbo/zacks-scraper/config"
+)
+
+// Returned by Fetch during a replay when nothing was archived for a key
+var ErrNotArchived = errors.New("no archived response")
+
+type Replay struct {
+	// Date looked up for responses fetched without a query date, i.e. the
+	// esp_filter and stock_screener jobs
+	Date time.Time
+}
+
+// Directory raw responses of a job are archived to
+func ArchiveDir(job *config.ScrapeJob) string {
+	if job.ArchiveDir != "" {
+		return job.ArchiveDir
+	}
+	return filepath.Join(job.OutDir, "_raw")
+}
+
+// Fetches a raw response from Zacks and archives it if the job has
+// archiveRaw set. During a replay the archived response matching key is
+// returned instead and fetch is never called, so jobs go through exactly
+// the same parsing and writing either way.
+//
+// key needs Kind, and Date and Tab where the request has them. An empty
+// Date means the day the response was fetched
+func (r *Run) Fetch(job *config.ScrapeJob, key archive.Entry, fetch func() ([]byte, *archive.Exchange, error)) ([]byte, *archive.Exchange, error) {
+	if r.Replay != nil {
+		if key.Date == "" {
+			key.Date = r.Replay.Date.Format("2006-01-02")
+		}
+		return r.replay(job, key)
+	}
+
+	body, exchange, err := fetch()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if job.ArchiveRaw {
+		key.Job = job.DisplayName()
+		key.JobType = job.JobType
+		key.RunID = r.ID
+		key.Exchange = *exchange
+		if key.Date == "" {
+			key.Date = exchange.FetchedAt.Format("2006-01-02")
+		}
+
+		err = archive.Open(ArchiveDir(job)).Save(key, body)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return body, exchange, nil
+}
+
+// Looks up the most recently archived response for key
+func (r *Run) replay(job *config.ScrapeJob, key archive.Entry) ([]byte, *archive.Exchange, error) {
+	store := archive.Open(ArchiveDir(job))
+
+	r.mu.Lock()
+	if r.archives == nil {
+		r.archives = map[string][]archive.Entry{}
+	}
+	entries, ok := r.archives[store.Dir()]
+	if !ok {
+		var err error
+		entries, err = store.Entries()
+		if err != nil {
+			r.mu.Unlock()
+			return nil, nil, err
+		}
+		r.archives[store.Dir()] = entries
+	}
+	r.mu.Unlock()
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Job != job.DisplayName() || e.JobType != job.JobType || e.Kind != key.Kind || e.Date != key.Date || e.Tab != key.Tab {
+			continue
+		}
+
+		body, err := store.Load(e)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error loading archived response: %w", err)
+		}
+
+		exchange := e.Exchange
+		return body, &exchange, nil
+	}
+
+	return nil, nil, fmt.Errorf("%w for %v %v on %v", ErrNotArchived, key.Kind, key.Tab, key.Date)
+}
@@ -0,0 +1,114 @@
+package output
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+
+	"github.com/iamburbo/zacks-scraper/config"
+)
+
+// An output file that only appears at its final path once it is
+// committed. Until then data is written to a hidden temp file in the same
+// directory, so a crash mid-write never leaves a truncated file behind
+// for downstream jobs to pick up
+type File struct {
+	run  *Run
+	job  *config.ScrapeJob
+	part Partition
+	path string
+
+	tmp  *os.File
+	hash hash.Hash
+	size int64
+	done bool
+
+	validation map[string]int // rows that failed each validation rule
+}
+
+func createFile(run *Run, job *config.ScrapeJob, p Partition, path string) (*File, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create local file: %w", err)
+	}
+
+	return &File{
+		run:  run,
+		job:  job,
+		part: p,
+		path: path,
+		tmp:  tmp,
+		hash: sha256.New(),
+	}, nil
+}
+
+// Final path of the file
+func (f *File) Path() string {
+	return f.path
+}
+
+func (f *File) Write(b []byte) (int, error) {
+	n, err := f.tmp.Write(b)
+	f.hash.Write(b[:n])
+	f.size += int64(n)
+	return n, err
+}
+
+// Moves the file to its final path and records it in the run manifest
+func (f *File) Commit(rows int, schema string) error {
+	if f.done {
+		return fmt.Errorf("output file already closed: %v", f.path)
+	}
+	f.done = true
+
+	err := f.tmp.Chmod(0644)
+	if err == nil {
+		err = f.tmp.Sync()
+	}
+	if closeErr := f.tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.tmp.Name(), f.path)
+	}
+	if err != nil {
+		os.Remove(f.tmp.Name())
+		return fmt.Errorf("failed to commit %v: %w", f.path, err)
+	}
+
+	f.run.record(f.job, ManifestEntry{
+		Path:       f.path,
+		Rows:       rows,
+		Bytes:      f.size,
+		Sha256:     hex.EncodeToString(f.hash.Sum(nil)),
+		Schema:     schema,
+		Job:        f.job.DisplayName(),
+		JobType:    f.job.JobType,
+		Tab:        f.part.Tab,
+		Date:       f.part.Date.Format("2006-01-02"),
+		Parameters: f.job.Parameters,
+		Validation: f.validation,
+	})
+
+	return nil
+}
+
+// Discards the file. Does nothing once the file has been committed, so
+// it is safe to defer
+func (f *File) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+
+	f.tmp.Close()
+	os.Remove(f.tmp.Name())
+}
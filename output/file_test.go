@@ -0,0 +1,130 @@
+package output
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/config"
+)
+
+func TestCommitAndManifest(t *testing.T) {
+	dir := t.TempDir()
+	run := NewRun()
+	job := &config.ScrapeJob{
+		JobType:    "esp_filter",
+		OutDir:     dir,
+		Parameters: []map[string]interface{}{{"filter_type": "buys"}},
+	}
+
+	f, err := run.Create(job, Partition{Date: time.Now(), Ext: "csv", FlatTemplate: "{run_id}.{ext}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := []byte("Symbol\nAAPL\n")
+	f.Write(content)
+
+	// Nothing shows up at the final path before commit
+	if _, err := os.Stat(f.Path()); !os.IsNotExist(err) {
+		t.Fatalf("output file exists before commit: %v", err)
+	}
+
+	err = f.Commit(1, "esp_filter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := os.ReadFile(f.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != string(content) {
+		t.Fatalf("unexpected file content: %q", written)
+	}
+
+	err = run.WriteManifests()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "_manifests", run.ID+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := &Manifest{}
+	err = json.Unmarshal(b, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(manifest.Files) != 1 {
+		t.Fatalf("expected 1 manifest entry, got %v", len(manifest.Files))
+	}
+
+	sum := sha256.Sum256(content)
+	entry := manifest.Files[0]
+	if entry.Path != run.ID+".csv" || entry.Rows != 1 || entry.Sha256 != hex.EncodeToString(sum[:]) || entry.Schema != "esp_filter" {
+		t.Fatalf("unexpected manifest entry: %+v", entry)
+	}
+	if entry.Parameters[0]["filter_type"] != "buys" {
+		t.Fatalf("missing source parameters: %+v", entry.Parameters)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	dir := t.TempDir()
+	run := NewRun()
+	job := &config.ScrapeJob{JobType: "esp_filter", OutDir: dir}
+
+	f, err := run.Create(job, Partition{Ext: "csv", FlatTemplate: "{run_id}.{ext}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("partial"))
+	f.Abort()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("aborted file left %v entries behind", len(entries))
+	}
+
+	if err := f.Commit(0, "esp_filter"); err == nil {
+		t.Fatal("expected commit after abort to fail")
+	}
+}
+
+// Two unnamed jobs of the same type sharing an outDir resolve to the same
+// path, and the second one fails instead of replacing the first one's file
+func TestCreateSamePathTwice(t *testing.T) {
+	dir := t.TempDir()
+	run := NewRun()
+	buys := &config.ScrapeJob{JobType: "esp_filter", OutDir: dir, Layout: LayoutPartitioned}
+	sells := &config.ScrapeJob{JobType: "esp_filter", OutDir: dir, Layout: LayoutPartitioned}
+	p := Partition{Date: time.Now(), Ext: "csv"}
+
+	f, err := run.Create(buys, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Abort()
+
+	if _, err := run.Create(sells, p); err == nil {
+		t.Fatal("expected an error writing the same path twice")
+	}
+
+	sells.Name = "sells"
+	f, err = run.Create(sells, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Abort()
+}
@@ -0,0 +1,122 @@
+package output
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/config"
+)
+
+// Output layouts selectable with a job's `layout` field
+const (
+	// Files are written the way each job always has, e.g.
+	// <outDir>/<runTime>/<timestamp>_<tab>.parquet for the earnings calendar
+	LayoutFlat = "flat"
+
+	// Hive-style key=value directories that DuckDB and Spark can query
+	// across runs, e.g.
+	// <outDir>/dataset=earnings_calendar/tab=sales/date=2024-01-22/part-<job>-<runId>.parquet
+	LayoutPartitioned = "partitioned"
+)
+
+// File template used by the partitioned layout. Jobs of the same type can
+// share an outDir, so files are named after the job as well as the run
+const PartitionedTemplate = "dataset={dataset}/tab={tab}/date={date}/part-{job}-{run_id}.{ext}"
+
+// Describes a single output file of a job. Its fields fill the
+// placeholders of the job's file template:
+//
+//	{dataset}   Dataset, defaults to the job type
+//	{tab}       Tab
+//	{date}      Date as 2006-01-02
+//	{timestamp} Date as 20060102150405
+//	{job}       Name of the job, defaults to the job type
+//	{run_id}    ID of the current run
+//	{run_time}  Start of the current run as 200601021504
+//	{ext}       Ext
+type Partition struct {
+	Dataset string
+	Tab     string
+	Date    time.Time
+	Ext     string
+
+	// Template used when the job has the flat layout and no fileTemplate
+	FlatTemplate string
+
+	// Where and when the data was fetched, for the provenance columns
+	Endpoint  string
+	ScrapedAt time.Time
+
+	// For tables gathered from a request per symbol or other key: where
+	// and when each row was fetched, by its value of SourceColumn. Rows
+	// without a source get Endpoint and ScrapedAt
+	SourceColumn string
+	Sources      map[string]Source
+
+	// Data quality checks run on the rows before they are written
+	Rules []Rule
+
+	// Leaves out the job's compute columns and where filter, for tables
+	// derived from one they were already applied to
+	SkipTransform bool
+
+	// Set by jobs writing tables with different columns, e.g. several tabs
+	// of the earnings calendar. Compute columns and where filters reading a
+	// column the table doesn't have are skipped for it instead of failing
+	// the job
+	SkipMissingColumns bool
+}
+
+// Keeps a job name with slashes in one path segment
+var jobNameCleaner = strings.NewReplacer("/", "_", "\\", "_")
+
+// Builds the path of a partition's output file inside the job's outDir.
+// Directory segments of the form key={placeholder} are dropped when the
+// placeholder is empty, so jobs without tabs don't end up with "tab="
+// directories
+func Path(job *config.ScrapeJob, run *Run, p Partition) (string, error) {
+	template := job.FileTemplate
+	if template == "" {
+		switch job.Layout {
+		case "", LayoutFlat:
+			template = p.FlatTemplate
+		case LayoutPartitioned:
+			template = PartitionedTemplate
+		default:
+			return "", fmt.Errorf("unknown output layout: %v", job.Layout)
+		}
+	}
+
+	if template == "" {
+		return "", fmt.Errorf("no file template for job %v", job.JobType)
+	}
+
+	dataset := p.Dataset
+	if dataset == "" {
+		dataset = job.JobType
+	}
+
+	r := strings.NewReplacer(
+		"{dataset}", dataset,
+		"{tab}", p.Tab,
+		"{date}", p.Date.Format("2006-01-02"),
+		"{timestamp}", p.Date.Format("20060102150405"),
+		"{job}", jobNameCleaner.Replace(job.DisplayName()),
+		"{run_id}", run.ID,
+		"{run_time}", run.StartedAt.Format("200601021504"),
+		"{ext}", p.Ext,
+	)
+
+	segments := []string{job.OutDir}
+	for _, segment := range strings.Split(template, "/") {
+		segment = r.Replace(segment)
+		if segment == "" || strings.HasSuffix(segment, "=") {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+
+	return filepath.Join(segments...), nil
+}
@@ -0,0 +1,70 @@
+package output
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/config"
+)
+
+func TestPath(t *testing.T) {
+	run := &Run{ID: "20240122093000", StartedAt: time.Date(2024, 1, 22, 9, 30, 0, 0, time.UTC)}
+	date := time.Date(2024, 1, 22, 6, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		job  config.ScrapeJob
+		p    Partition
+		want string
+	}{
+		{
+			name: "flat",
+			job:  config.ScrapeJob{JobType: "earnings_calendar", OutDir: "out"},
+			p:    Partition{Tab: "sales", Date: date, Ext: "parquet", FlatTemplate: "{run_time}/{timestamp}_{tab}.{ext}"},
+			want: "out/202401220930/20240122060000_sales.parquet",
+		},
+		{
+			name: "partitioned",
+			job:  config.ScrapeJob{JobType: "earnings_calendar", OutDir: "out", Layout: LayoutPartitioned},
+			p:    Partition{Tab: "sales", Date: date, Ext: "parquet"},
+			want: "out/dataset=earnings_calendar/tab=sales/date=2024-01-22/part-earnings_calendar-20240122093000.parquet",
+		},
+		{
+			name: "partitioned without tab",
+			job:  config.ScrapeJob{JobType: "esp_filter", OutDir: "out", Layout: LayoutPartitioned},
+			p:    Partition{Date: date, Ext: "csv"},
+			want: "out/dataset=esp_filter/date=2024-01-22/part-esp_filter-20240122093000.csv",
+		},
+		{
+			name: "partitioned named job",
+			job:  config.ScrapeJob{Name: "buys/daily", JobType: "esp_filter", OutDir: "out", Layout: LayoutPartitioned},
+			p:    Partition{Date: date, Ext: "csv"},
+			want: "out/dataset=esp_filter/date=2024-01-22/part-buys_daily-20240122093000.csv",
+		},
+		{
+			name: "custom template",
+			job:  config.ScrapeJob{JobType: "earnings_release", OutDir: "out", FileTemplate: "{dataset}/{date}/{run_id}.{ext}"},
+			p:    Partition{Date: date, Ext: "parquet"},
+			want: "out/earnings_release/2024-01-22/20240122093000.parquet",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := Path(&tt.job, run, tt.p)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.name, err)
+		}
+		if got != filepath.FromSlash(tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPathUnknownLayout(t *testing.T) {
+	job := &config.ScrapeJob{JobType: "esp_filter", OutDir: "out", Layout: "nested"}
+	_, err := Path(job, &Run{ID: "1"}, Partition{FlatTemplate: "{run_id}.csv"})
+	if err == nil {
+		t.Fatal("expected error for unknown layout")
+	}
+}
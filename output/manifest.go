@@ -0,0 +1,176 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/config"
+)
+
+// Lists every file a run wrote to one output directory. Written to
+// <outDir>/_manifests/<runId>.json once all jobs have finished
+type Manifest struct {
+	RunID      string          `json:"runId"`
+	StartedAt  time.Time       `json:"startedAt"`
+	FinishedAt time.Time       `json:"finishedAt"`
+	Replay     bool            `json:"replay,omitempty"` // outputs were re-parsed from archived responses
+	Files      []ManifestEntry `json:"files"`
+	Validation map[string]int  `json:"validation,omitempty"` // rows that failed each rule, across files
+}
+
+type ManifestEntry struct {
+	Path       string                   `json:"path"` // relative to outDir
+	Rows       int                      `json:"rows"`
+	Bytes      int64                    `json:"bytes"`
+	Sha256     string                   `json:"sha256"`
+	Schema     string                   `json:"schema"`
+	Job        string                   `json:"job,omitempty"` // the job's display name
+	JobType    string                   `json:"jobType"`
+	Tab        string                   `json:"tab,omitempty"`
+	Date       string                   `json:"date"`
+	Parameters []map[string]interface{} `json:"parameters"`
+	Validation map[string]int           `json:"validation,omitempty"` // rows that failed each rule
+}
+
+// Adds a committed file to the manifest of the job's outDir. A file
+// committed again by a retried job replaces its earlier entry
+func (r *Run) record(job *config.ScrapeJob, entry ManifestEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rel, err := filepath.Rel(job.OutDir, entry.Path); err == nil {
+		entry.Path = filepath.ToSlash(rel)
+	}
+
+	if r.manifests == nil {
+		r.manifests = map[string][]ManifestEntry{}
+	}
+
+	entries := r.manifests[job.OutDir]
+	for i, e := range entries {
+		if e.Path == entry.Path {
+			entries[i] = entry
+			return
+		}
+	}
+	r.manifests[job.OutDir] = append(entries, entry)
+}
+
+// Writes one manifest per output directory used during the run
+func (r *Run) WriteManifests() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dirs := []string{}
+	for dir := range r.manifests {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	finishedAt := time.Now()
+	for _, dir := range dirs {
+		manifest := &Manifest{
+			RunID:      r.ID,
+			StartedAt:  r.StartedAt,
+			FinishedAt: finishedAt,
+			Replay:     r.Replay != nil,
+			Files:      r.manifests[dir],
+		}
+		for _, f := range manifest.Files {
+			for rule, n := range f.Validation {
+				if manifest.Validation == nil {
+					manifest.Validation = map[string]int{}
+				}
+				manifest.Validation[rule] += n
+			}
+		}
+
+		err := writeJSONAtomic(filepath.Join(dir, "_manifests", r.ID+".json"), manifest)
+		if err != nil {
+			return fmt.Errorf("error writing manifest: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Returns the path of the file of a schema that the job wrote in its most
+// recent earlier run, read from the manifests in its outDir, or "" if
+// there is none. Replays are only compared with replays
+func (r *Run) PreviousOutput(job *config.ScrapeJob, schema string) (string, error) {
+	paths, err := filepath.Glob(filepath.Join(job.OutDir, "_manifests", "*.json"))
+	if err != nil {
+		return "", err
+	}
+
+	var latest *Manifest
+	var path string
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return "", err
+		}
+		m := &Manifest{}
+		err = json.Unmarshal(b, m)
+		if err != nil {
+			return "", fmt.Errorf("error reading manifest %v: %w", p, err)
+		}
+
+		if m.RunID == r.ID || m.Replay != (r.Replay != nil) {
+			continue
+		}
+		if latest != nil && !m.StartedAt.After(latest.StartedAt) {
+			continue
+		}
+
+		for _, f := range m.Files {
+			// Manifests from before jobs were recorded only have the type
+			sameJob := f.Job == job.DisplayName() || (f.Job == "" && f.JobType == job.JobType)
+			if f.Schema == schema && sameJob {
+				latest = m
+				path = filepath.Join(job.OutDir, filepath.FromSlash(f.Path))
+			}
+		}
+	}
+
+	return path, nil
+}
+
+func writeJSONAtomic(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, b)
+}
+
+func writeFileAtomic(path string, b []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(b)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
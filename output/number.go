@@ -0,0 +1,25 @@
+package output
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var numberCleaner = strings.NewReplacer(",", "", "$", "", "%", "", " ", "")
+
+// Parses a number as Zacks shows it, e.g. +3.81%, 1,234.5, $2.10 or
+// -0.05. Missing values (empty, -- or NA) are nil. Used by every job and by
+// the where/compute expressions, so a cell reads the same everywhere
+func ParseNumber(v string) (*float64, error) {
+	v = strings.TrimSpace(v)
+	if isMissing(v) {
+		return nil, nil
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimPrefix(numberCleaner.Replace(v), "+"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a number", v)
+	}
+	return &f, nil
+}
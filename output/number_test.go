@@ -0,0 +1,31 @@
+package output
+
+import "testing"
+
+func TestParseNumber(t *testing.T) {
+	cases := map[string]float64{
+		"+3.81%":    3.81,
+		"1,234.5":   1234.5,
+		"$2.10":     2.10,
+		"-0.05":     -0.05,
+		" -$1.20 ":  -1.20,
+		"2,955,390": 2955390,
+	}
+	for v, want := range cases {
+		got, err := ParseNumber(v)
+		if err != nil || got == nil || *got != want {
+			t.Fatalf("ParseNumber(%q) = %v, %v, want %v", v, got, err, want)
+		}
+	}
+
+	for _, v := range []string{"", "--", "NA"} {
+		got, err := ParseNumber(v)
+		if err != nil || got != nil {
+			t.Fatalf("expected %q to be missing, got %v, %v", v, got, err)
+		}
+	}
+
+	if _, err := ParseNumber("After Close"); err == nil {
+		t.Fatal("expected an error for text")
+	}
+}
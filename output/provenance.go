@@ -0,0 +1,59 @@
+package output
+
+import (
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/config"
+)
+
+// Names of the provenance columns, in the order they are added
+var ProvenanceColumns = []string{"scraped_at", "run_id", "job_name", "query_date", "source_endpoint", "zacks_tab"}
+
+// Where and when part of a table's rows was fetched
+type Source struct {
+	Endpoint  string
+	ScrapedAt time.Time
+}
+
+// Appends the provenance columns to every row of t, so rows can be traced
+// back to the run, job and query that produced them once they land in a
+// warehouse
+func addProvenance(t *Table, run *Run, job *config.ScrapeJob, p Partition) {
+	constant := func(v interface{}) func(int) interface{} {
+		return func(int) interface{} { return v }
+	}
+	orNil := func(v string) interface{} {
+		if v == "" {
+			return nil
+		}
+		return v
+	}
+
+	source := func(r int) Source {
+		if i := t.Index(p.SourceColumn); p.SourceColumn != "" && i >= 0 {
+			if s, ok := p.Sources[t.Columns[i].Format(t.Rows[r][i])]; ok {
+				return s
+			}
+		}
+		return Source{Endpoint: p.Endpoint, ScrapedAt: p.ScrapedAt}
+	}
+
+	var queryDate interface{}
+	if !p.Date.IsZero() {
+		queryDate = p.Date
+	}
+
+	t.AddColumn(Column{Name: "scraped_at", Type: Timestamp, Optional: true}, func(r int) interface{} {
+		if s := source(r); !s.ScrapedAt.IsZero() {
+			return s.ScrapedAt
+		}
+		return nil
+	})
+	t.AddColumn(Column{Name: "run_id", Type: String, Optional: true}, constant(run.ID))
+	t.AddColumn(Column{Name: "job_name", Type: String, Optional: true}, constant(job.DisplayName()))
+	t.AddColumn(Column{Name: "query_date", Type: Date, Optional: true}, constant(queryDate))
+	t.AddColumn(Column{Name: "source_endpoint", Type: String, Optional: true}, func(r int) interface{} {
+		return orNil(source(r).Endpoint)
+	})
+	t.AddColumn(Column{Name: "zacks_tab", Type: String, Optional: true}, constant(orNil(p.Tab)))
+}
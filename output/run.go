@@ -0,0 +1,106 @@
+package output
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/archive"
+	"github.com/iamburbo/zacks-scraper/config"
+)
+
+// A single execution of the scraper. Every file written during the run
+// carries its ID so outputs from different runs never collide
+type Run struct {
+	ID        string
+	StartedAt time.Time
+
+	// Set when the run re-parses archived responses instead of fetching
+	// from Zacks
+	Replay *Replay
+
+	mu        sync.Mutex
+	manifests map[string][]ManifestEntry // by outDir
+	written   map[string]string          // job name by output path
+	archives  map[string][]archive.Entry // replay index by archive dir
+}
+
+func NewRun() *Run {
+	now := time.Now()
+	return &Run{
+		ID:        now.Format("20060102150405"),
+		StartedAt: now,
+		manifests: map[string][]ManifestEntry{},
+	}
+}
+
+// Creates the output file for a partition of a job. The file has to be
+// committed before it shows up at its final path. A path can only be
+// written once per run, so jobs sharing an outDir never replace each
+// other's files
+func (r *Run) Create(job *config.ScrapeJob, p Partition) (*File, error) {
+	path, err := Path(job, r, p)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if r.written == nil {
+		r.written = map[string]string{}
+	}
+	other, ok := r.written[path]
+	if !ok {
+		r.written[path] = job.DisplayName()
+	}
+	r.mu.Unlock()
+	if ok {
+		return nil, fmt.Errorf("%v was already written by job %v in this run, give the jobs different names or outDirs", path, other)
+	}
+
+	return createFile(r, job, p, path)
+}
+
+// Writes a table as the output file of a partition. The format follows the
+// partition's extension. The job's compute columns and where filter are
+// applied first, then rows are checked against the partition's rules, and
+// a rule with the fail action returns a *ValidationError
+func (r *Run) WriteTable(job *config.ScrapeJob, p Partition, t *Table) error {
+	if !p.SkipTransform {
+		err := transform(job, t, p.SkipMissingColumns)
+		if err != nil {
+			return err
+		}
+	}
+
+	counts, err := validate(job, t, p.Rules)
+	if err != nil {
+		return err
+	}
+
+	if job.Provenance {
+		addProvenance(t, r, job, p)
+	}
+
+	f, err := r.Create(job, p)
+	if err != nil {
+		return err
+	}
+
+	switch p.Ext {
+	case "parquet":
+		err = t.WriteParquet(f)
+	case "csv":
+		err = t.WriteCSV(f)
+	default:
+		err = fmt.Errorf("unknown output format: %v", p.Ext)
+	}
+	if err != nil {
+		f.Abort()
+		return fmt.Errorf("error writing %v: %w", f.Path(), err)
+	}
+
+	if len(counts) > 0 {
+		f.validation = counts
+	}
+	return f.Commit(len(t.Rows), t.Schema)
+}
@@ -0,0 +1,296 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+type ColumnType int
+
+// Go values held by a table for each column type
+const (
+	String    ColumnType = iota // string
+	Float                       // float64
+	Int                         // int64
+	Bool                        // bool
+	Date                        // time.Time, only the calendar day is kept
+	Timestamp                   // time.Time
+)
+
+type Column struct {
+	Name     string
+	Type     ColumnType
+	Optional bool // nil values are allowed
+}
+
+// Rows of a dataset ready to be written, independent of the output format.
+// Lets jobs add columns (e.g. provenance) that aren't part of the row
+// structs they parse into
+type Table struct {
+	Schema  string // name recorded in the run manifest
+	Columns []Column
+	Rows    [][]interface{}
+}
+
+// Builds a table from a slice of pointers to row structs. Columns are
+// named after the `parquet:"name=..."` tag of each field; pointer fields
+// become optional columns
+func NewTable(schema string, rows interface{}) (*Table, error) {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("table rows must be a slice, got %T", rows)
+	}
+
+	rowType := v.Type().Elem()
+	if rowType.Kind() == reflect.Ptr {
+		rowType = rowType.Elem()
+	}
+	if rowType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("table rows must be structs, got %v", rowType)
+	}
+
+	t := &Table{Schema: schema}
+	for i := 0; i < rowType.NumField(); i++ {
+		field := rowType.Field(i)
+		tag := field.Tag.Get("parquet")
+		if tag == "" {
+			continue
+		}
+
+		c, err := columnFromField(field, tag)
+		if err != nil {
+			return nil, err
+		}
+		t.Columns = append(t.Columns, c)
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		row := reflect.Indirect(v.Index(i))
+		values := []interface{}{}
+		for j := 0; j < rowType.NumField(); j++ {
+			if rowType.Field(j).Tag.Get("parquet") == "" {
+				continue
+			}
+
+			field := row.Field(j)
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					values = append(values, nil)
+					continue
+				}
+				field = field.Elem()
+			}
+			values = append(values, field.Interface())
+		}
+		t.Rows = append(t.Rows, values)
+	}
+
+	return t, nil
+}
+
+func columnFromField(field reflect.StructField, tag string) (Column, error) {
+	c := Column{}
+	for _, part := range strings.Split(tag, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) == 2 && strings.ToLower(kv[0]) == "name" {
+			c.Name = kv[1]
+		}
+	}
+	if c.Name == "" {
+		return c, fmt.Errorf("field %v has no parquet name", field.Name)
+	}
+
+	fieldType := field.Type
+	if fieldType.Kind() == reflect.Ptr {
+		c.Optional = true
+		fieldType = fieldType.Elem()
+	}
+
+	switch {
+	case fieldType == reflect.TypeOf(time.Time{}):
+		c.Type = Timestamp
+		if strings.Contains(tag, "convertedtype=DATE") {
+			c.Type = Date
+		}
+	case fieldType.Kind() == reflect.String:
+		c.Type = String
+	case fieldType.Kind() == reflect.Float64:
+		c.Type = Float
+	case fieldType.Kind() == reflect.Int64:
+		c.Type = Int
+	case fieldType.Kind() == reflect.Bool:
+		c.Type = Bool
+	default:
+		return c, fmt.Errorf("unsupported type %v for field %v", fieldType, field.Name)
+	}
+
+	return c, nil
+}
+
+// Builds a table of string columns from CSV records. The first record is
+// the header
+func TableFromRecords(schema string, records [][]string) *Table {
+	t := &Table{Schema: schema}
+	if len(records) == 0 {
+		return t
+	}
+
+	for _, name := range records[0] {
+		t.Columns = append(t.Columns, Column{Name: name, Type: String})
+	}
+
+	for _, record := range records[1:] {
+		values := make([]interface{}, len(record))
+		for i, v := range record {
+			values[i] = v
+		}
+		t.Rows = append(t.Rows, values)
+	}
+
+	return t
+}
+
+// The table as CSV records, header first
+func (t *Table) Records() [][]string {
+	header := []string{}
+	for _, c := range t.Columns {
+		header = append(header, c.Name)
+	}
+
+	records := [][]string{header}
+	for _, row := range t.Rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = t.Columns[i].Format(v)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// Appends a column, calling value for each row index
+func (t *Table) AddColumn(c Column, value func(i int) interface{}) {
+	t.Columns = append(t.Columns, c)
+	for i := range t.Rows {
+		t.Rows[i] = append(t.Rows[i], value(i))
+	}
+}
+
+// Index of the named column, or -1
+func (t *Table) Index(name string) int {
+	for i, c := range t.Columns {
+		if c.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *Table) WriteParquet(w io.Writer) error {
+	md := []string{}
+	for _, c := range t.Columns {
+		md = append(md, c.parquetTag())
+	}
+
+	pw, err := writer.NewCSVWriterFromWriter(md, w, 4)
+	if err != nil {
+		return fmt.Errorf("can't create parquet writer: %w", err)
+	}
+
+	pw.RowGroupSize = 128 * 1024 * 1024 //128M
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+
+	for _, row := range t.Rows {
+		rec := make([]interface{}, len(row))
+		for i, v := range row {
+			rec[i] = t.Columns[i].parquetValue(v)
+		}
+
+		if err = pw.Write(rec); err != nil {
+			return fmt.Errorf("write error: %w", err)
+		}
+	}
+
+	if err = pw.WriteStop(); err != nil {
+		return fmt.Errorf("write stop error: %w", err)
+	}
+
+	return nil
+}
+
+func (t *Table) WriteCSV(w io.Writer) error {
+	return csv.NewWriter(w).WriteAll(t.Records())
+}
+
+// Formats a value of the column the way it is written to CSV
+func (c Column) Format(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		if c.Type == Date {
+			return v.Format("2006-01-02")
+		}
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (c Column) parquetTag() string {
+	var tag string
+	switch c.Type {
+	case String:
+		tag = "type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"
+	case Float:
+		tag = "type=DOUBLE"
+	case Int:
+		tag = "type=INT64"
+	case Bool:
+		tag = "type=BOOLEAN"
+	case Date:
+		tag = "type=INT32, convertedtype=DATE"
+	case Timestamp:
+		tag = "type=INT64, convertedtype=TIMESTAMP_MILLIS"
+	}
+
+	tag = "name=" + c.Name + ", " + tag
+	if c.Optional {
+		tag += ", repetitiontype=OPTIONAL"
+	}
+	return tag
+}
+
+// Converts a table value to the primitive parquet-go expects
+func (c Column) parquetValue(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	switch c.Type {
+	case Date:
+		t := v.(time.Time)
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return int32(day.Unix() / 86400)
+	case Timestamp:
+		return v.(time.Time).UnixMilli()
+	default:
+		return v
+	}
+}
@@ -0,0 +1,85 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/config"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+type testRow struct {
+	Symbol   string   `parquet:"name=symbol, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Estimate *float64 `parquet:"name=estimate, type=DOUBLE, repetitiontype=OPTIONAL"`
+	internal string
+}
+
+func TestNewTable(t *testing.T) {
+	estimate := 1.25
+	table, err := NewTable("test", []*testRow{
+		{Symbol: "AAPL", Estimate: &estimate},
+		{Symbol: "MSFT"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(table.Columns) != 2 || table.Columns[1].Type != Float || !table.Columns[1].Optional {
+		t.Fatalf("unexpected columns: %+v", table.Columns)
+	}
+	if table.Rows[0][1] != 1.25 || table.Rows[1][1] != nil {
+		t.Fatalf("unexpected rows: %+v", table.Rows)
+	}
+
+	var b bytes.Buffer
+	err = table.WriteParquet(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := buffer.NewBufferFile(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, err := reader.NewParquetReader(f, nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.GetNumRows() != 2 {
+		t.Fatalf("expected 2 rows, got %v", pr.GetNumRows())
+	}
+}
+
+func TestWriteTableProvenance(t *testing.T) {
+	dir := t.TempDir()
+	run := &Run{ID: "20240122093000"}
+	job := &config.ScrapeJob{Name: "morning", JobType: "esp_filter", OutDir: dir, Provenance: true}
+	scrapedAt := time.Date(2024, 1, 22, 9, 30, 0, 0, time.UTC)
+
+	table := TableFromRecords("esp_filter", [][]string{{"Symbol"}, {"AAPL"}})
+	err := run.WriteTable(job, Partition{
+		Date:         scrapedAt,
+		Ext:          "csv",
+		FlatTemplate: "{run_id}.{ext}",
+		Endpoint:     "https://www.zacks.com/esp",
+		ScrapedAt:    scrapedAt,
+	}, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(dir + "/20240122093000.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "Symbol,scraped_at,run_id,job_name,query_date,source_endpoint,zacks_tab\n" +
+		"AAPL,2024-01-22T09:30:00Z,20240122093000,morning,2024-01-22,https://www.zacks.com/esp,\n"
+	if string(b) != want {
+		t.Fatalf("unexpected output:\n%v", strings.TrimSpace(string(b)))
+	}
+}
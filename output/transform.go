@@ -0,0 +1,150 @@
+package output
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/config"
+)
+
+// Adds the job's compute columns to a table, then keeps the rows matching
+// its where expression. Computed columns can use the ones before them, and
+// where can use them all.
+//
+// An expression reading a column the table doesn't have is an error, most
+// likely a typo. Jobs writing tables with different columns, e.g. the tabs
+// of the earnings calendar, set skipMissing to skip it for that table with
+// a log line instead
+func transform(job *config.ScrapeJob, t *Table, skipMissing bool) error {
+	for i, c := range job.Compute {
+		if c.Name == "" {
+			return fmt.Errorf("computed column without a name: %q", c.Expr)
+		}
+		if t.Index(c.Name) >= 0 {
+			return fmt.Errorf("computed column %v is already a column of %v", c.Name, t.Schema)
+		}
+
+		expr, err := ParseExpr(c.Expr)
+		if err != nil {
+			return fmt.Errorf("compute %v: %w", c.Name, err)
+		}
+		if missing := missingColumn(expr, t); missing != "" {
+			for _, later := range job.Compute[i+1:] {
+				if later.Name == missing {
+					return fmt.Errorf("compute %v: %v is computed after it", c.Name, missing)
+				}
+			}
+			if !skipMissing {
+				return fmt.Errorf("compute %v: %v has no column %v", c.Name, t.Schema, missing)
+			}
+			log.Printf("compute %v skipped for %v, which has no column %v", c.Name, t.Schema, missing)
+			continue
+		}
+
+		values := make([]interface{}, len(t.Rows))
+		for r := range t.Rows {
+			values[r], err = expr.Eval(rowEnv(t, r))
+			if err != nil {
+				return fmt.Errorf("compute %v: %w", c.Name, err)
+			}
+		}
+
+		column := Column{Name: c.Name, Type: valueType(values), Optional: true}
+		if column.Type == String {
+			for r, v := range values {
+				if v != nil {
+					values[r] = toString(v)
+				}
+			}
+		}
+		t.AddColumn(column, func(r int) interface{} {
+			return values[r]
+		})
+	}
+
+	if job.Where == "" {
+		return nil
+	}
+
+	expr, err := ParseExpr(job.Where)
+	if err != nil {
+		return fmt.Errorf("where: %w", err)
+	}
+	if missing := missingColumn(expr, t); missing != "" {
+		if !skipMissing {
+			return fmt.Errorf("where: %v has no column %v", t.Schema, missing)
+		}
+		log.Printf("where %v skipped for %v, which has no column %v", expr, t.Schema, missing)
+		return nil
+	}
+
+	rows := t.Rows[:0]
+	for r, row := range t.Rows {
+		v, err := expr.Eval(rowEnv(t, r))
+		if err != nil {
+			return fmt.Errorf("where: %w", err)
+		}
+
+		switch v := v.(type) {
+		case bool:
+			if v {
+				rows = append(rows, row)
+			}
+		case nil:
+		default:
+			return fmt.Errorf("where: %v is %v, not true or false", expr, v)
+		}
+	}
+
+	if dropped := len(t.Rows) - len(rows); dropped > 0 {
+		log.Printf("where %v left out %v rows of %v", expr, dropped, t.Schema)
+	}
+	t.Rows = rows
+	return nil
+}
+
+// The first column an expression reads that the table doesn't have, or ""
+func missingColumn(expr *Expr, t *Table) string {
+	for _, c := range expr.Columns() {
+		if t.Index(c) < 0 {
+			return c
+		}
+	}
+	return ""
+}
+
+func rowEnv(t *Table, r int) Env {
+	return func(column string) (interface{}, bool) {
+		i := t.Index(column)
+		if i < 0 {
+			return nil, false
+		}
+		return t.Rows[r][i], true
+	}
+}
+
+// Column type holding computed values. Mixed values are written as text
+func valueType(values []interface{}) ColumnType {
+	types := map[ColumnType]bool{}
+	for _, v := range values {
+		switch v.(type) {
+		case nil:
+		case float64:
+			types[Float] = true
+		case bool:
+			types[Bool] = true
+		case time.Time:
+			types[Date] = true
+		default:
+			types[String] = true
+		}
+	}
+
+	if len(types) == 1 {
+		for t := range types {
+			return t
+		}
+	}
+	return String
+}
@@ -0,0 +1,107 @@
+package output
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/iamburbo/zacks-scraper/config"
+)
+
+func TestTransform(t *testing.T) {
+	job := &config.ScrapeJob{
+		Compute: []config.ComputedColumn{
+			{Name: "surprisePct", Expr: "(reported - estimate) / estimate * 100"},
+			{Name: "beat", Expr: "surprisePct > 0"},
+		},
+		Where: "surprisePct > 10 or symbol = 'MSFT'",
+	}
+	table := TableFromRecords("test", [][]string{
+		{"symbol", "estimate", "reported"},
+		{"AAPL", "2.00", "2.10"},
+		{"CAT", "1.00", "1.50"},
+		{"MSFT", "2.00", "--"},
+	})
+
+	err := transform(job, table, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{
+		{"symbol", "estimate", "reported", "surprisePct", "beat"},
+		{"CAT", "1.00", "1.50", "50", "true"},
+		{"MSFT", "2.00", "--", "", ""},
+	}
+	if records := table.Records(); !reflect.DeepEqual(records, expected) {
+		t.Fatalf("expected %q, got %q", expected, records)
+	}
+	if table.Columns[3].Type != Float || table.Columns[4].Type != Bool {
+		t.Fatalf("unexpected column types: %+v", table.Columns)
+	}
+}
+
+func TestTransformInvalid(t *testing.T) {
+	cases := map[string]*config.ScrapeJob{
+		"not a condition":  {Where: "estimate + 1"},
+		"existing column":  {Compute: []config.ComputedColumn{{Name: "symbol", Expr: "lower(symbol)"}}},
+		"missing name":     {Compute: []config.ComputedColumn{{Expr: "lower(symbol)"}}},
+		"invalid compute":  {Compute: []config.ComputedColumn{{Name: "x", Expr: "estimate +"}}},
+		"later column ref": {Compute: []config.ComputedColumn{{Name: "x", Expr: "y"}, {Name: "y", Expr: "1"}}},
+	}
+
+	for name, job := range cases {
+		table := TableFromRecords("test", [][]string{
+			{"symbol", "estimate"},
+			{"AAPL", "2.00"},
+		})
+		err := transform(job, table, true)
+		if err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
+
+func TestTransformMissingColumn(t *testing.T) {
+	job := &config.ScrapeJob{
+		Compute: []config.ComputedColumn{
+			{Name: "surprisePct", Expr: "(reported - estimate) / estimate * 100"},
+			{Name: "loud", Expr: "upper(symbol)"},
+		},
+		Where: "surprisePct > 10",
+	}
+	table := TableFromRecords("test", [][]string{
+		{"symbol", "estimate"},
+		{"AAPL", "2.00"},
+	})
+
+	// The table has no reported column, so surprisePct and the where
+	// expression reading it are skipped
+	err := transform(job, table, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{
+		{"symbol", "estimate", "loud"},
+		{"AAPL", "2.00", "AAPL"},
+	}
+	if records := table.Records(); !reflect.DeepEqual(records, expected) {
+		t.Fatalf("expected %q, got %q", expected, records)
+	}
+
+	// A job writing a single table fails instead, as the column name is
+	// most likely a typo
+	for _, job := range []*config.ScrapeJob{
+		{Where: "estimat > 1"},
+		{Compute: []config.ComputedColumn{{Name: "x", Expr: "estimat * 2"}}},
+	} {
+		table := TableFromRecords("test", [][]string{
+			{"symbol", "estimate"},
+			{"AAPL", "2.00"},
+		})
+		err := transform(job, table, false)
+		if err == nil {
+			t.Fatalf("expected an error for %+v", job)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package output
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/config"
+)
+
+// What happens to rows failing a rule
+const (
+	ActionDrop = "drop" // leave the rows out of the output
+	ActionWarn = "warn" // log and keep the rows (default)
+	ActionFail = "fail" // fail the job without writing the output
+)
+
+// A data quality check on the rows of a table
+type Rule struct {
+	Name string
+	// Reports the rows that fail. Called with the whole table so rules can
+	// compare rows with each other
+	Check func(t *Table) []bool
+}
+
+// Returned when rows fail a rule whose action is fail
+type ValidationError struct {
+	Schema string
+	Rule   string
+	Rows   int
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v rows of %v failed validation rule %v", e.Rows, e.Schema, e.Rule)
+}
+
+var symbolPattern = regexp.MustCompile(`^[A-Z][A-Z0-9.\-]{0,9}$`)
+
+// Symbols must look like a ticker, e.g. AAPL or BRK.B
+func SymbolFormat(column string) Rule {
+	return columnRule("symbol_format", column, func(v string) bool {
+		return symbolPattern.MatchString(v)
+	})
+}
+
+// Estimates must be numbers. Zacks marks missing values with -- or NA
+func NumericEstimate(column string) Rule {
+	return columnRule("numeric_estimate", column, func(v string) bool {
+		_, err := ParseNumber(v)
+		return err == nil
+	})
+}
+
+// Dates must fall on a day from `from` to `to`, inclusive
+func DateInRange(column, layout string, from, to time.Time) Rule {
+	first := day(from)
+	last := day(to)
+	return columnRule("date_in_range", column, func(v string) bool {
+		if isMissing(v) {
+			return true
+		}
+		d, err := time.Parse(layout, v)
+		if err != nil {
+			return false
+		}
+		return !d.Before(first) && !d.After(last)
+	})
+}
+
+// A symbol may only appear once per output file, i.e. once per date and tab
+func UniqueSymbol(column string) Rule {
+	return uniqueRule("unique_symbol", column)
+}
+
+// The values of several columns may only appear together once per output
+// file, e.g. a symbol and event type in the events dataset
+func UniqueKey(columns ...string) Rule {
+	return uniqueRule("unique_key", columns...)
+}
+
+func uniqueRule(name string, columns ...string) Rule {
+	return Rule{
+		Name: name,
+		Check: func(t *Table) []bool {
+			failed := make([]bool, len(t.Rows))
+			indexes := []int{}
+			for _, c := range columns {
+				i := t.Index(c)
+				if i < 0 {
+					return failed
+				}
+				indexes = append(indexes, i)
+			}
+
+			seen := map[string]bool{}
+			for r, row := range t.Rows {
+				key := ""
+				for _, i := range indexes {
+					key += t.Columns[i].Format(row[i]) + "\x00"
+				}
+				if seen[key] {
+					failed[r] = true
+				}
+				seen[key] = true
+			}
+			return failed
+		},
+	}
+}
+
+func columnRule(name, column string, valid func(v string) bool) Rule {
+	return Rule{
+		Name: name,
+		Check: func(t *Table) []bool {
+			failed := make([]bool, len(t.Rows))
+			i := t.Index(column)
+			if i < 0 {
+				return failed
+			}
+
+			for r, row := range t.Rows {
+				failed[r] = !valid(t.Columns[i].Format(row[i]))
+			}
+			return failed
+		},
+	}
+}
+
+func isMissing(v string) bool {
+	return v == "" || v == "--" || v == "NA"
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// The action configured for a rule, falling back to the job's default
+func ruleAction(job *config.ScrapeJob, rule string) (string, error) {
+	action, ok := job.Validation[rule]
+	if !ok {
+		action, ok = job.Validation["default"]
+	}
+	if !ok {
+		return ActionWarn, nil
+	}
+
+	switch action {
+	case ActionDrop, ActionWarn, ActionFail:
+		return action, nil
+	default:
+		return "", fmt.Errorf("unknown validation action for %v: %v", rule, action)
+	}
+}
+
+// Runs the rules over a table, dropping rows or failing as configured.
+// Returns the number of rows that failed each rule
+func validate(job *config.ScrapeJob, t *Table, rules []Rule) (map[string]int, error) {
+	counts := map[string]int{}
+	drop := make([]bool, len(t.Rows))
+
+	for _, rule := range rules {
+		action, err := ruleAction(job, rule.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		n := 0
+		for r, failed := range rule.Check(t) {
+			if !failed {
+				continue
+			}
+			n++
+			if action == ActionDrop {
+				drop[r] = true
+			}
+		}
+		if n == 0 {
+			continue
+		}
+		counts[rule.Name] += n
+
+		switch action {
+		case ActionFail:
+			return counts, &ValidationError{Schema: t.Schema, Rule: rule.Name, Rows: n}
+		case ActionDrop:
+			log.Printf("dropping %v rows of %v that failed %v", n, t.Schema, rule.Name)
+		default:
+			log.Printf("%v rows of %v failed %v", n, t.Schema, rule.Name)
+		}
+	}
+
+	rows := t.Rows[:0]
+	for r, row := range t.Rows {
+		if !drop[r] {
+			rows = append(rows, row)
+		}
+	}
+	t.Rows = rows
+
+	return counts, nil
+}
@@ -0,0 +1,97 @@
+package output
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/config"
+)
+
+func testValidationTable() *Table {
+	return TableFromRecords("test", [][]string{
+		{"symbol", "estimate", "exDivDate"},
+		{"AAPL", "2.10", "1/22/2024"},
+		{"", "1.00", "1/22/2024"},
+		{"MSFT", "n/a", "--"},
+		{"AAPL", "--", "2/9/2024"},
+	})
+}
+
+var testRules = []Rule{
+	SymbolFormat("symbol"),
+	NumericEstimate("estimate"),
+	DateInRange("exDivDate", "1/2/2006", time.Date(2024, 1, 22, 6, 0, 0, 0, time.UTC), time.Date(2024, 1, 22, 6, 0, 0, 0, time.UTC)),
+	UniqueSymbol("symbol"),
+}
+
+func TestValidate(t *testing.T) {
+	job := &config.ScrapeJob{Validation: map[string]string{
+		"default":       ActionDrop,
+		"unique_symbol": ActionWarn,
+	}}
+	table := testValidationTable()
+
+	counts, err := validate(job, table, testRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]int{"symbol_format": 1, "numeric_estimate": 1, "date_in_range": 1, "unique_symbol": 1}
+	for rule, n := range expected {
+		if counts[rule] != n {
+			t.Fatalf("expected %v %v failures, got %v", n, rule, counts)
+		}
+	}
+
+	// Only the rows failing the dropped rules are left out
+	if len(table.Rows) != 1 || table.Rows[0][0] != "AAPL" {
+		t.Fatalf("unexpected rows: %v", table.Rows)
+	}
+}
+
+func TestValidateFail(t *testing.T) {
+	job := &config.ScrapeJob{
+		OutDir:     t.TempDir(),
+		Validation: map[string]string{"symbol_format": ActionFail},
+	}
+	run := NewRun()
+
+	err := run.WriteTable(job, Partition{Ext: "csv", Rules: testRules}, testValidationTable())
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Rule != "symbol_format" || validationErr.Rows != 1 {
+		t.Fatalf("expected symbol_format to fail, got %v", err)
+	}
+	if len(run.manifests[job.OutDir]) != 0 {
+		t.Fatal("expected no output to be written")
+	}
+}
+
+func TestValidateUnknownAction(t *testing.T) {
+	job := &config.ScrapeJob{Validation: map[string]string{"default": "ignore"}}
+
+	_, err := validate(job, testValidationTable(), testRules)
+	if err == nil {
+		t.Fatal("expected an error for an unknown action")
+	}
+}
+
+func TestUniqueKey(t *testing.T) {
+	table := TableFromRecords("test", [][]string{
+		{"symbol", "event_type"},
+		{"AAPL", "earnings"},
+		{"AAPL", "dividend"},
+		{"AAPL", "earnings"},
+	})
+
+	failed := UniqueKey("symbol", "event_type").Check(table)
+	if failed[0] || failed[1] || !failed[2] {
+		t.Fatalf("expected only the repeated key to fail, got %v", failed)
+	}
+
+	// Tables without the columns pass
+	if failed := UniqueKey("symbol", "period").Check(table); failed[2] {
+		t.Fatalf("expected no failures, got %v", failed)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/config"
+	"github.com/iamburbo/zacks-scraper/output"
+)
+
+type replayOptions struct {
+	StartDate time.Time
+	EndDate   time.Time
+	Job       string // only replay the job with this name
+	OutDir    string // write outputs under this directory instead of each job's outDir
+}
+
+// Parses the arguments of the replay command:
+//
+//	./zacks-scraper replay --config=config.yml --start_date=2023-01-23 [--end_date=2023-01-27] [--job=name] [--outDir=dir]
+func parseReplayOptionsFromArgs() (*replayOptions, error) {
+	opts := &replayOptions{}
+
+	start, err := config.ParseArgFromArgs("start_date")
+	if err != nil {
+		return nil, err
+	}
+	if start == "" {
+		return nil, errors.New("replay needs --start_date")
+	}
+	opts.StartDate, err = time.Parse("2006-01-02", start)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := config.ParseArgFromArgs("end_date")
+	if err != nil {
+		return nil, err
+	}
+	opts.EndDate = opts.StartDate
+	if end != "" {
+		opts.EndDate, err = time.Parse("2006-01-02", end)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	opts.Job, err = config.ParseArgFromArgs("job")
+	if err != nil {
+		return nil, err
+	}
+
+	opts.OutDir, err = config.ParseArgFromArgs("outDir")
+	if err != nil {
+		return nil, err
+	}
+
+	return opts, nil
+}
+
+// Re-parses the archived raw responses of each job with the current parsers
+// and writes new outputs, without contacting Zacks. Jobs run exactly as
+// they would live, except that every response comes from the job's archive
+func replay(cfg *config.Config, opts *replayOptions) error {
+	run := output.NewRun()
+	run.Replay = &output.Replay{}
+
+	// Never used, every response comes from the archive
+	client := &http.Client{}
+
+	for _, job := range cfg.Jobs {
+		if opts.Job != "" && job.DisplayName() != opts.Job {
+			continue
+		}
+
+		if opts.OutDir != "" {
+			job.ArchiveDir = output.ArchiveDir(&job)
+			job.OutDir = filepath.Join(opts.OutDir, job.OutDir)
+		}
+
+		var err error
+		switch job.JobType {
+		case "earnings_calendar", "earnings_release":
+			job.Parameters = replayDateParameters(job.Parameters, opts.StartDate, opts.EndDate)
+			err = runJob(&job, client, run)
+		default:
+			// Jobs without dates are replayed once per day they were archived on
+			for d := opts.StartDate; !d.After(opts.EndDate); d = d.AddDate(0, 0, 1) {
+				run.Replay.Date = d
+				err = runJob(&job, client, run)
+				if err != nil {
+					break
+				}
+			}
+		}
+
+		if err != nil {
+			return fmt.Errorf("error replaying job %v: %w", job.DisplayName(), err)
+		}
+	}
+
+	return run.WriteManifests()
+}
+
+// Replaces the date parameters of a job with the replayed range
+func replayDateParameters(parameters []map[string]interface{}, start, end time.Time) []map[string]interface{} {
+	replaced := []map[string]interface{}{}
+	for _, p := range parameters {
+		kept := map[string]interface{}{}
+		for k, v := range p {
+			switch k {
+			case "start_date", "end_date", "start_date_offset", "end_date_offset":
+			default:
+				kept[k] = v
+			}
+		}
+
+		if len(kept) > 0 {
+			replaced = append(replaced, kept)
+		}
+	}
+
+	return append(replaced,
+		map[string]interface{}{"start_date": start.Format("2006-01-02")},
+		map[string]interface{}{"end_date": end.Format("2006-01-02")},
+	)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/archive"
+	"github.com/iamburbo/zacks-scraper/config"
+	"github.com/iamburbo/zacks-scraper/output"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+const archivedCalendarBody = `window.app_data = {"data":[["<span class=\"hoverquote-symbol\">AAPL<span class=\"sr-only\"></span></span>","<span title=\"Apple Inc.\" >Apple Inc.</span>","2,400,000","After Close","1.94","2.18","<div class=\"right pos positive pos_plus\">0.24</div>","<div class=\"right pos positive pos_plus\">12.37%</div>","<div class=\"right pos positive pos_plus\">1.2%</div>"]]}`
+
+func TestReplay(t *testing.T) {
+	outDir := t.TempDir()
+	job := config.ScrapeJob{
+		JobType: "earnings_calendar",
+		OutDir:  outDir,
+		Layout:  output.LayoutPartitioned,
+		Parameters: []map[string]interface{}{
+			{"start_date": "NOW"},
+			{"tabs": []interface{}{"earnings"}},
+		},
+	}
+
+	err := archive.Open(output.ArchiveDir(&job)).Save(archive.Entry{
+		Job:     "earnings_calendar",
+		JobType: "earnings_calendar",
+		Kind:    archive.KindCalendarJSON,
+		Date:    "2024-01-22",
+		Tab:     "earnings",
+		Exchange: archive.Exchange{
+			Method:    "GET",
+			URL:       "https://www.zacks.com/includes/classes/z2_class_calendarfunctions_data.php",
+			Status:    200,
+			FetchedAt: time.Date(2024, 1, 22, 8, 0, 0, 0, time.UTC),
+		},
+	}, []byte(archivedCalendarBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayDir := t.TempDir()
+	err = replay(&config.Config{Jobs: []config.ScrapeJob{job}}, &replayOptions{
+		StartDate: time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 1, 22, 0, 0, 0, 0, time.UTC),
+		OutDir:    replayDir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the archived day is written, the other is skipped
+	files, err := filepath.Glob(filepath.Join(replayDir, outDir, "dataset=earnings_calendar", "tab=earnings", "*", "*.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || filepath.Base(filepath.Dir(files[0])) != "date=2024-01-22" {
+		t.Fatalf("unexpected replay outputs: %v", files)
+	}
+
+	f, err := local.NewLocalFileReader(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	pr, err := reader.NewParquetReader(f, nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.GetNumRows() != 1 {
+		t.Fatalf("expected 1 row, got %v", pr.GetNumRows())
+	}
+}
+
+func TestReplayDateParameters(t *testing.T) {
+	params := replayDateParameters([]map[string]interface{}{
+		{"start_date_offset": "-1"},
+		{"tabs": []interface{}{"sales"}},
+	}, time.Date(2024, 1, 22, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 23, 0, 0, 0, 0, time.UTC))
+
+	if len(params) != 3 || params[1]["start_date"] != "2024-01-22" || params[2]["end_date"] != "2024-01-23" {
+		t.Fatalf("unexpected parameters: %v", params)
+	}
+}
@@ -0,0 +1,69 @@
+package stockscreener
+
+// Categories of the Zacks criteria picker
+const (
+	categoryDescriptors = "Company Descriptors"
+	categorySize        = "Size & Share Volume"
+	categoryPrice       = "Price & Price Changes"
+	categoryRanking     = "Zacks Rank & Style Scores"
+	categoryBroker      = "Broker Rating"
+	categorySurprise    = "Earnings & Sales Surprise"
+	categoryRevisions   = "Estimate Revisions"
+	categoryEstimates   = "EPS & Sales Estimates"
+	categoryValuation   = "Valuation"
+	categoryDividend    = "Dividend"
+)
+
+// Criteria of the stock screener. Adding a criterion only takes a row here.
+//
+// Codes, keys and names are those the scraper sent before the catalog
+// existed. The rest of the screener's criteria need their code and key
+// taken from a captured request before they can be added
+//
+// TODO: Fetch the criteria definitions from the screener-api page the
+// session already visits, cache them and check these codes against them.
+// zackstest's screener_api.html is a placeholder without criteria, so it
+// has to be replaced with a capture of the page first
+var Catalog = []Criterion{
+	// ID, Code, Key, Name, Category, Type, Unit
+
+	// Company descriptors
+	{"optionable", "11015", "12", "Optionable", categoryDescriptors, YesNo, ""},
+
+	// Size & share volume
+	{"market_cap", "12010", "8", "Market Cap (mil)", categorySize, Number, "mil"},
+	{"avg_volume", "12015", "15", "Avg Volume", categorySize, Number, "shares"},
+
+	// Price & price changes
+	{"52_week_high", "14010", "7", "52 Week High", categoryPrice, Number, "$"},
+
+	// Zacks rank & style scores
+	{"zacks_rank", "15005", "0", "Zacks Rank", categoryRanking, Rank, ""},
+	{"zacks_industry_rank", "15025", "1", "Zacks Industry Rank", categoryRanking, Number, ""},
+	{"value_score", "15030", "2", "Value Score", categoryRanking, Grade, ""},
+	{"growth_score", "15035", "3", "Growth Score", categoryRanking, Grade, ""},
+	{"momentum_score", "15040", "4", "Momentum Score", categoryRanking, Grade, ""},
+	{"vgm_score", "15045", "5", "VGM Score", categoryRanking, Grade, ""},
+
+	// Broker rating
+	{"num_brokers", "16010", "11", "# of Brokers in Rating", categoryBroker, Number, ""},
+
+	// Earnings & sales surprise
+	{"last_eps_surprise", "17005", "9", "Last EPS Surprise (%)", categorySurprise, Number, "%"},
+	{"last_reported_quarter", "17030", "68", "Last Reported Qtr (yyyymm)", categorySurprise, Quarter, "yyyymm"},
+	{"last_eps_report_date", "17050", "72", "Last EPS Report Date (yyyymmdd)", categorySurprise, Date, "yyyymmdd"},
+	{"next_eps_report_date", "17055", "73", "Next EPS Report Date (yyyymmdd)", categorySurprise, Date, "yyyymmdd"},
+	{"earnings_esp", "17060", "6", "Earnings ESP", categorySurprise, Number, "%"},
+
+	// Estimate revisions
+	{"percent_change_f1", "18020", "13", "% Change F1 Est. (4 weeks)", categoryRevisions, Number, "%"},
+
+	// EPS & sales estimates
+	{"q0_consensus_est", "19005", "80", "Q0 Consensus Est. (last completed fiscal Qtr)", categoryEstimates, Number, "$"},
+
+	// Valuation
+	{"p_n_e", "22010", "10", "P/E (F1)", categoryValuation, Number, ""},
+
+	// Dividend
+	{"div_yield", "25005", "14", "Div. Yield %", categoryDividend, Number, "%"},
+}
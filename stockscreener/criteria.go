@@ -0,0 +1,134 @@
+package stockscreener
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Kind of value a criterion is compared with
+type ValueType string
+
+const (
+	Number  ValueType = "number"  // any number, e.g. 2.5 or -10
+	Rank    ValueType = "rank"    // 1 (Strong Buy) to 5 (Strong Sell)
+	Grade   ValueType = "grade"   // style score, A to F
+	YesNo   ValueType = "yes_no"  // YES or NO
+	Date    ValueType = "date"    // yyyymmdd
+	Quarter ValueType = "quarter" // yyyymm
+)
+
+// Operators are sent as integer codes, which depend on the value type
+var (
+	numberOperators = map[string]int{
+		">=": 6,
+		"<=": 7,
+		"=":  8,
+		"<>": 17,
+	}
+	gradeOperators = map[string]int{
+		">=": 12,
+		"<=": 13,
+		"=":  19,
+		"<>": 20,
+	}
+	yesNoOperators = map[string]int{
+		"EQUAL":     9,
+		"NOT EQUAL": 18,
+	}
+)
+
+// Operators allowed for a value type, by their config spelling
+func (t ValueType) Operators() map[string]int {
+	switch t {
+	case Grade:
+		return gradeOperators
+	case YesNo:
+		return yesNoOperators
+	default:
+		return numberOperators
+	}
+}
+
+var (
+	datePattern    = regexp.MustCompile(`^\d{8}$`)
+	quarterPattern = regexp.MustCompile(`^\d{6}$`)
+)
+
+// Checks a value from the config against the type
+func (t ValueType) Validate(value string) error {
+	switch t {
+	case Number:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%v is not a number", value)
+		}
+	case Rank:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 5 {
+			return fmt.Errorf("%v is not a rank from 1 to 5", value)
+		}
+	case Grade:
+		switch value {
+		case "A", "B", "C", "D", "F":
+		default:
+			return fmt.Errorf("%v is not a grade from A to F", value)
+		}
+	case YesNo:
+		if value != "YES" && value != "NO" {
+			return fmt.Errorf("%v is not YES or NO", value)
+		}
+	case Date:
+		if !datePattern.MatchString(value) {
+			return fmt.Errorf("%v is not a yyyymmdd date", value)
+		}
+	case Quarter:
+		if !quarterPattern.MatchString(value) {
+			return fmt.Errorf("%v is not a yyyymm quarter", value)
+		}
+	}
+	return nil
+}
+
+// A field the screener can filter on
+type Criterion struct {
+	ID       string    // used in the config
+	Code     string    // p_items code
+	Key      string    // p_item_key, Zacks' fixed position of the criterion
+	Name     string    // p_item_name, as shown by Zacks
+	Category string    // group in the Zacks criteria picker
+	Type     ValueType // determines the allowed values and operators
+	Unit     string    // e.g. "%", "$" or "mil"
+}
+
+var criteriaByID = func() map[string]Criterion {
+	m := map[string]Criterion{}
+	for _, c := range Catalog {
+		if _, ok := m[c.ID]; ok {
+			panic("duplicate criterion id: " + c.ID)
+		}
+		m[c.ID] = c
+	}
+	return m
+}()
+
+// Looks up a criterion of the catalog by its config id
+func LookupCriterion(id string) (Criterion, bool) {
+	c, ok := criteriaByID[id]
+	return c, ok
+}
+
+// Operator code for the criterion, or an error listing the allowed ones
+func (c Criterion) OperatorCode(operator string) (int, error) {
+	operators := c.Type.Operators()
+	code, ok := operators[operator]
+	if !ok {
+		allowed := []string{}
+		for o := range operators {
+			allowed = append(allowed, o)
+		}
+		sort.Strings(allowed)
+		return 0, fmt.Errorf("unknown operator for %v: %v, expected one of %v", c.ID, operator, allowed)
+	}
+	return code, nil
+}
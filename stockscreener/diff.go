@@ -0,0 +1,197 @@
+package stockscreener
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/iamburbo/zacks-scraper/archive"
+	"github.com/iamburbo/zacks-scraper/config"
+	"github.com/iamburbo/zacks-scraper/output"
+	"github.com/iamburbo/zacks-scraper/zacks"
+)
+
+// Columns of the changed dataset, after the key columns
+const (
+	fieldColumn    = "field"
+	previousColumn = "previous"
+	currentColumn  = "current"
+)
+
+// Fields compared between runs: ranks, scores and prices, e.g. Zacks Rank,
+// VGM Score and Last Close
+func isTrackedField(column string) bool {
+	for _, suffix := range []string{"Rank", "Score", "Price", "Close"} {
+		if strings.HasSuffix(column, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Compares the results written with those of the job's previous run and
+// writes the tickers added to and removed from the results, and the
+// tracked fields that changed for the others. Does nothing on a job's
+// first run
+func writeDiff(job *config.ScrapeJob, run *output.Run, opts *resultOptions, current *output.Table, exchange *archive.Exchange) error {
+	if !opts.Diff {
+		return nil
+	}
+
+	path, err := run.PreviousOutput(job, "stock_screener")
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		log.Printf("no previous %v results to diff with", job.DisplayName())
+		return nil
+	}
+
+	previous, err := readResults(path)
+	if os.IsNotExist(err) {
+		log.Printf("previous %v results are gone, not diffing: %v", job.DisplayName(), path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading previous results: %w", err)
+	}
+
+	added, removed, changed := diffResults(withoutProvenance(previous), withoutProvenance(current.Records()))
+
+	datasets := []struct {
+		name    string
+		records [][]string
+	}{
+		{"added", added},
+		{"removed", removed},
+		{"changed", changed},
+	}
+	for _, d := range datasets {
+		err = run.WriteTable(job, output.Partition{
+			Dataset:       "stock_screener_" + d.name,
+			Date:          exchange.FetchedAt,
+			Ext:           "csv",
+			FlatTemplate:  "{timestamp}_" + d.name + ".{ext}",
+			Endpoint:      zacks.ScreenerExportURL(),
+			ScrapedAt:     exchange.FetchedAt,
+			SkipTransform: true,
+		}, output.TableFromRecords("stock_screener_"+d.name, d.records))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Diffs two results, header first. Rows are matched on their ticker, and
+// on their screen when both results come from named screens. Added and
+// removed rows are returned as they are, changes as one row per tracked
+// field with its previous and current value
+func diffResults(previous, current [][]string) (added, removed, changed [][]string) {
+	keys := []string{tickerColumn}
+	if indexOf(previous[0], screenNameColumn) >= 0 && indexOf(current[0], screenNameColumn) >= 0 {
+		keys = []string{screenNameColumn, tickerColumn}
+	}
+
+	key := func(header, row []string) string {
+		parts := []string{}
+		for _, k := range keys {
+			if i := indexOf(header, k); i >= 0 {
+				parts = append(parts, row[i])
+			}
+		}
+		return strings.Join(parts, "\x00")
+	}
+
+	previousRows := map[string][]string{}
+	for _, row := range previous[1:] {
+		previousRows[key(previous[0], row)] = row
+	}
+	currentRows := map[string][]string{}
+	for _, row := range current[1:] {
+		currentRows[key(current[0], row)] = row
+	}
+
+	// Fields both results have, in the order of the current one
+	fields := []string{}
+	for _, c := range current[0] {
+		if isTrackedField(c) && indexOf(previous[0], c) >= 0 {
+			fields = append(fields, c)
+		}
+	}
+
+	added = [][]string{current[0]}
+	changed = [][]string{append(append([]string{}, keys...), fieldColumn, previousColumn, currentColumn)}
+	for _, row := range current[1:] {
+		k := key(current[0], row)
+		old, ok := previousRows[k]
+		if !ok {
+			added = append(added, row)
+			continue
+		}
+
+		for _, f := range fields {
+			before := old[indexOf(previous[0], f)]
+			after := row[indexOf(current[0], f)]
+			if before == after {
+				continue
+			}
+
+			record := []string{}
+			for _, c := range keys {
+				record = append(record, row[indexOf(current[0], c)])
+			}
+			changed = append(changed, append(record, f, before, after))
+		}
+	}
+
+	removed = [][]string{previous[0]}
+	for _, row := range previous[1:] {
+		if _, ok := currentRows[key(previous[0], row)]; !ok {
+			removed = append(removed, row)
+		}
+	}
+
+	return added, removed, changed
+}
+
+// Reads results written by an earlier run
+func readResults(path string) ([][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%v is empty", path)
+	}
+	return records, nil
+}
+
+// Leaves out the provenance columns, which differ on every run
+func withoutProvenance(records [][]string) [][]string {
+	keep := []int{}
+	for i, c := range records[0] {
+		if indexOf(output.ProvenanceColumns, c) < 0 {
+			keep = append(keep, i)
+		}
+	}
+
+	kept := [][]string{}
+	for _, record := range records {
+		row := make([]string, len(keep))
+		for i, k := range keep {
+			row[i] = record[k]
+		}
+		kept = append(kept, row)
+	}
+	return kept
+}
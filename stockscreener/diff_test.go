@@ -0,0 +1,157 @@
+package stockscreener
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/config"
+	"github.com/iamburbo/zacks-scraper/output"
+	"github.com/iamburbo/zacks-scraper/zacks"
+	"github.com/iamburbo/zacks-scraper/zackstest"
+)
+
+func TestDiffResults(t *testing.T) {
+	previous := [][]string{
+		{"Company Name", "Ticker", "Last Close", "Zacks Rank", "VGM Score", "Market Cap (mil)"},
+		{"Apple Inc.", "AAPL", "191.56", "3", "C", "2955390.00"},
+		{"Caterpillar Inc.", "CAT", "297.10", "2", "A", "150680.00"},
+	}
+	current := [][]string{
+		{"Company Name", "Ticker", "Last Close", "Zacks Rank", "VGM Score", "Market Cap (mil)"},
+		{"Apple Inc.", "AAPL", "194.17", "2", "C", "2995390.00"},
+		{"NVIDIA Corporation", "NVDA", "615.27", "1", "B", "1519680.00"},
+	}
+
+	added, removed, changed := diffResults(previous, current)
+
+	if !reflect.DeepEqual(added, [][]string{current[0], current[2]}) {
+		t.Fatalf("unexpected added: %q", added)
+	}
+	if !reflect.DeepEqual(removed, [][]string{previous[0], previous[2]}) {
+		t.Fatalf("unexpected removed: %q", removed)
+	}
+
+	// Market cap isn't tracked
+	expected := [][]string{
+		{"Ticker", "field", "previous", "current"},
+		{"AAPL", "Last Close", "191.56", "194.17"},
+		{"AAPL", "Zacks Rank", "3", "2"},
+	}
+	if !reflect.DeepEqual(changed, expected) {
+		t.Fatalf("expected %q, got %q", expected, changed)
+	}
+}
+
+func TestDiffResultsNamedScreens(t *testing.T) {
+	previous := [][]string{
+		{"screen_name", "Ticker", "Zacks Rank"},
+		{"value", "AAPL", "3"},
+	}
+	current := [][]string{
+		{"screen_name", "Ticker", "Zacks Rank"},
+		{"value", "AAPL", "2"},
+		{"growth", "AAPL", "2"},
+	}
+
+	added, removed, changed := diffResults(previous, current)
+
+	if len(added) != 2 || added[1][0] != "growth" {
+		t.Fatalf("unexpected added: %q", added)
+	}
+	if len(removed) != 1 {
+		t.Fatalf("unexpected removed: %q", removed)
+	}
+	expected := [][]string{
+		{"screen_name", "Ticker", "field", "previous", "current"},
+		{"value", "AAPL", "Zacks Rank", "3", "2"},
+	}
+	if !reflect.DeepEqual(changed, expected) {
+		t.Fatalf("expected %q, got %q", expected, changed)
+	}
+}
+
+func TestRunScreenDiff(t *testing.T) {
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &config.ScrapeJob{
+		JobType:      "stock_screener",
+		OutDir:       t.TempDir(),
+		FileTemplate: "{run_id}/{dataset}.{ext}",
+		Provenance:   true,
+		Parameters: []map[string]interface{}{
+			{"id": "zacks_rank", "value": "1", "operator": ">="},
+			{"diff": true},
+		},
+	}
+
+	// Nothing to diff with on the first run
+	first := output.NewRun()
+	first.ID = "first"
+	err = RunStockScreener(job, client, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = first.WriteManifests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(job.OutDir, "first", "stock_screener_added.csv")); len(files) != 0 {
+		t.Fatalf("unexpected diff on the first run: %v", files)
+	}
+
+	server.SetFixture("screener_export.csv", []byte(`"Company Name","Ticker","Last Close","Zacks Rank","Value Score","Growth Score","Momentum Score","VGM Score","Market Cap (mil)"
+"Apple Inc.","AAPL","194.17","2","C","B","D","C","2995390.00"
+"NVIDIA Corporation","NVDA","615.27","1","D","A","B","B","1519680.00"
+"Microsoft Corporation","MSFT","397.58","3","D","B","C","C","2955120.00"
+`))
+
+	second := output.NewRun()
+	second.ID = "second"
+	second.StartedAt = first.StartedAt.Add(time.Minute)
+	err = RunStockScreener(job, client, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) [][]string {
+		records, err := readResults(filepath.Join(job.OutDir, "second", name+".csv"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return records
+	}
+
+	added := read("stock_screener_added")
+	if len(added) != 2 || added[1][1] != "MSFT" {
+		t.Fatalf("unexpected added: %q", added)
+	}
+	removed := read("stock_screener_removed")
+	if len(removed) != 2 || removed[1][1] != "CAT" {
+		t.Fatalf("unexpected removed: %q", removed)
+	}
+
+	// Provenance columns aren't compared
+	changed := read("stock_screener_changed")
+	expected := [][]string{
+		{"Ticker", "field", "previous", "current"},
+		{"AAPL", "Last Close", "191.56", "194.17"},
+		{"AAPL", "Zacks Rank", "3", "2"},
+	}
+	if !reflect.DeepEqual(changed[0][:4], expected[0]) || len(changed) != len(expected) {
+		t.Fatalf("expected %q, got %q", expected, changed)
+	}
+	for i := range expected[1:] {
+		if !reflect.DeepEqual(changed[i+1][:4], expected[i+1]) {
+			t.Fatalf("expected %q, got %q", expected, changed)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package stockscreener
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A criterion compared with a value, as sent in one row of "My Criteria"
+type Condition struct {
+	Criterion Criterion
+	Operator  string // config spelling, e.g. ">=" or "EQUAL"
+	Value     string
+}
+
+var (
+	comparisonPattern = regexp.MustCompile(`^\s*(\w+)\s*(>=|<=|<>|!=|=)\s*(\S+)\s*$`)
+	betweenPattern    = regexp.MustCompile(`(?i)^\s*(\w+)\s+between\s+(\S+)\s+and\s+(\S+)\s*$`)
+)
+
+// Parses a criterion expression such as "zacks_rank <= 2", "optionable = yes"
+// or "market_cap between 300 and 2000". A range expands into a >= and a <=
+// condition. Conditions are checked against the catalog
+func ParseExpression(expr string) ([]Condition, error) {
+	if m := betweenPattern.FindStringSubmatch(expr); m != nil {
+		low, err := newCondition(m[1], ">=", m[2])
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", expr, err)
+		}
+		if low.Criterion.Type == YesNo {
+			return nil, fmt.Errorf("%q: %v is YES or NO and has no range", expr, low.Criterion.ID)
+		}
+		high, err := newCondition(m[1], "<=", m[3])
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", expr, err)
+		}
+
+		x, errLow := strconv.ParseFloat(low.Value, 64)
+		y, errHigh := strconv.ParseFloat(high.Value, 64)
+		if errLow == nil && errHigh == nil && x > y {
+			return nil, fmt.Errorf("%q: range is empty", expr)
+		}
+		return []Condition{low, high}, nil
+	}
+
+	m := comparisonPattern.FindStringSubmatch(expr)
+	if m == nil {
+		return nil, fmt.Errorf("%q: expected <criterion> <operator> <value> or <criterion> between <low> and <high>", expr)
+	}
+
+	c, err := newCondition(m[1], m[2], m[3])
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", expr, err)
+	}
+	return []Condition{c}, nil
+}
+
+// Builds a condition from an expression, spelling the operator and value
+// the way the criterion's type takes them
+func newCondition(id, operator, value string) (Condition, error) {
+	criterion, ok := LookupCriterion(id)
+	if !ok {
+		return Condition{}, fmt.Errorf("unknown screener criterion: %v", id)
+	}
+
+	if operator == "!=" {
+		operator = "<>"
+	}
+
+	switch criterion.Type {
+	case YesNo:
+		switch operator {
+		case "=":
+			operator = "EQUAL"
+		case "<>":
+			operator = "NOT EQUAL"
+		}
+		value = strings.ToUpper(value)
+	case Grade:
+		value = strings.ToUpper(value)
+	}
+
+	return checkCondition(Condition{criterion, operator, value})
+}
+
+// Checks the operator and value of a condition against its criterion's type
+func checkCondition(c Condition) (Condition, error) {
+	_, err := c.Criterion.OperatorCode(c.Operator)
+	if err != nil {
+		return c, err
+	}
+
+	err = c.Criterion.Type.Validate(c.Value)
+	if err != nil {
+		return c, fmt.Errorf("invalid value for %v: %w", c.Criterion.ID, err)
+	}
+	return c, nil
+}
+
+// Parameters loading screens stored on Zacks, which the scraper can't run:
+// the requests listing and loading saved and predefined screens haven't
+// been captured
+var storedScreenParameters = []string{"saved_screen", "predefined_screen"}
+
+// Collects the conditions of a job's parameters, given either as
+// id/value/operator items or as expressions under "criteria"
+func parseConditions(config []map[string]interface{}) ([]Condition, error) {
+	conditions := []Condition{}
+
+	for _, item := range config {
+		if isResultParameter(item) {
+			continue
+		}
+
+		for _, p := range storedScreenParameters {
+			if _, ok := item[p]; ok {
+				return nil, fmt.Errorf("%v isn't supported, give the screen's criteria instead", p)
+			}
+		}
+
+		if v, ok := item["criteria"]; ok {
+			expressions := []interface{}{v}
+			if list, ok := v.([]interface{}); ok {
+				expressions = list
+			}
+			for _, e := range expressions {
+				expr, ok := e.(string)
+				if !ok {
+					return nil, fmt.Errorf("criteria must be strings, got %v", e)
+				}
+				c, err := ParseExpression(expr)
+				if err != nil {
+					return nil, err
+				}
+				conditions = append(conditions, c...)
+			}
+			continue
+		}
+
+		c, err := parseConditionItem(item)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, c)
+	}
+
+	return conditions, nil
+}
+
+func parseConditionItem(item map[string]interface{}) (Condition, error) {
+	id, ok := item["id"].(string)
+	if !ok {
+		return Condition{}, errors.New("each item in query list must have 'id' field")
+	}
+
+	value, ok := item["value"]
+	if !ok {
+		return Condition{}, errors.New("each item in query list must have 'value' field")
+	}
+
+	operator, ok := item["operator"].(string)
+	if !ok {
+		return Condition{}, errors.New("each item in query list must have 'operator' field")
+	}
+
+	criterion, ok := LookupCriterion(id)
+	if !ok {
+		return Condition{}, fmt.Errorf("unknown screener criterion: %v", id)
+	}
+
+	return checkCondition(Condition{criterion, operator, fmt.Sprint(value)})
+}
@@ -0,0 +1,73 @@
+package stockscreener
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExpression(t *testing.T) {
+	cases := map[string][][2]string{
+		"zacks_rank <= 2":                  {{"<=", "2"}},
+		"value_score>=b":                   {{">=", "B"}},
+		"optionable = yes":                 {{"EQUAL", "YES"}},
+		"optionable != no":                 {{"NOT EQUAL", "NO"}},
+		"market_cap between 300 and 2000":  {{">=", "300"}, {"<=", "2000"}},
+		"market_cap BETWEEN -1.5 AND 2000": {{">=", "-1.5"}, {"<=", "2000"}},
+	}
+
+	for expr, expected := range cases {
+		conditions, err := ParseExpression(expr)
+		if err != nil {
+			t.Errorf("%v: %v", expr, err)
+			continue
+		}
+
+		got := [][2]string{}
+		for _, c := range conditions {
+			got = append(got, [2]string{c.Operator, c.Value})
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%v: expected %v, got %v", expr, expected, got)
+		}
+	}
+}
+
+func TestParseExpressionInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"zacks_rank",
+		"zacks_rank > 2",
+		"shoe_size >= 10",
+		"zacks_rank <= 9",
+		"value_score >= E",
+		"optionable >= yes",
+		"optionable between no and yes",
+		"market_cap between 2000 and 300",
+	} {
+		_, err := ParseExpression(expr)
+		if err == nil {
+			t.Errorf("expected an error for %q", expr)
+		}
+	}
+}
+
+func TestWriteQueryExpressions(t *testing.T) {
+	form, err := writeTestQuery([]map[string]interface{}{
+		{"criteria": []interface{}{"zacks_rank <= 1", "market_cap between 300 and 2000"}},
+		{"criteria": "optionable = yes"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"operator[]":   {"7", "6", "7", "9"},
+		"value[]":      {"1", "300", "2000", "YES"},
+		"p_items[]":    {"15005", "12010", "12010", "11015"},
+		"p_item_key[]": {"0", "8", "8", "12"},
+	}
+	for field, values := range expected {
+		if !reflect.DeepEqual(form[field], values) {
+			t.Fatalf("unexpected %v: %v", field, form[field])
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package stockscreener
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Columns added when a job runs several named screens
+const (
+	screenNameColumn  = "screen_name"
+	screensColumn     = "screens"
+	screenCountColumn = "screen_count"
+)
+
+// One of the screens of a job. Name is "" for a job running a single,
+// unnamed screen
+type namedScreen struct {
+	Name       string
+	Parameters []map[string]interface{}
+}
+
+// Splits a job's parameters into its screens. A job lists named screens
+// under "screens", each with a name and either its own parameters list or
+// the keys of a single parameter item, e.g.
+//
+//	parameters:
+//	    - screens:
+//	        - name: value
+//	          criteria: ["zacks_rank <= 2", "value_score >= B"]
+//	        - name: bull
+//	          criteria: "momentum_score = A"
+//
+// Without "screens", the job's parameters are one unnamed screen
+func parseNamedScreens(parameters []map[string]interface{}) ([]namedScreen, error) {
+	var list []interface{}
+	found := false
+	for _, p := range parameters {
+		v, ok := p["screens"]
+		if !ok {
+			continue
+		}
+		if found {
+			return nil, errors.New("screens can only be given once")
+		}
+		found = true
+
+		list, ok = v.([]interface{})
+		if !ok || len(list) == 0 {
+			return nil, fmt.Errorf("screens must be a list of screens, got %v", v)
+		}
+	}
+
+	if !found {
+		return []namedScreen{{Parameters: parameters}}, nil
+	}
+
+	// Only the result options apply to every screen
+	for _, p := range parameters {
+		if _, ok := p["screens"]; !ok && !isResultParameter(p) {
+			return nil, fmt.Errorf("%v must be set inside a screen when screens are given", p)
+		}
+	}
+
+	screens := []namedScreen{}
+	names := map[string]bool{}
+	for i, v := range list {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("screen %v must be a map, got %v", i, v)
+		}
+
+		name := fmt.Sprint(m["name"])
+		if _, ok := m["name"]; !ok || name == "" {
+			return nil, fmt.Errorf("screen %v has no name", i)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate screen name: %v", name)
+		}
+		names[name] = true
+
+		screen := namedScreen{Name: name}
+		if p, ok := m["parameters"]; ok {
+			items, ok := p.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("parameters of screen %v must be a list, got %v", name, p)
+			}
+			for _, item := range items {
+				itemMap, ok := item.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("parameters of screen %v must be maps, got %v", name, item)
+				}
+				screen.Parameters = append(screen.Parameters, itemMap)
+			}
+		}
+		item := map[string]interface{}{}
+		for k, v := range m {
+			if k != "name" && k != "parameters" {
+				item[k] = v
+			}
+		}
+		if len(item) > 0 {
+			screen.Parameters = append(screen.Parameters, item)
+		}
+
+		for _, p := range screen.Parameters {
+			if isResultParameter(p) {
+				return nil, fmt.Errorf("screen %v: result options apply to the whole job and must be set outside screens", name)
+			}
+		}
+		if len(screen.Parameters) == 0 {
+			return nil, fmt.Errorf("screen %v has no criteria", name)
+		}
+
+		screens = append(screens, screen)
+	}
+
+	return screens, nil
+}
+
+// Stacks the results of named screens into one table, with the name of the
+// screen each row came from first. Columns are matched by name, so screens
+// exporting different views still line up
+func combineScreens(names []string, results [][][]string) [][]string {
+	header := []string{screenNameColumn}
+	position := map[string]int{}
+	for _, records := range results {
+		if len(records) == 0 {
+			continue
+		}
+		for _, c := range records[0] {
+			if _, ok := position[c]; !ok {
+				position[c] = len(header)
+				header = append(header, c)
+			}
+		}
+	}
+
+	combined := [][]string{header}
+	for s, records := range results {
+		if len(records) == 0 {
+			continue
+		}
+		for _, record := range records[1:] {
+			row := make([]string, len(header))
+			row[0] = names[s]
+			for i, c := range records[0] {
+				row[position[c]] = record[i]
+			}
+			combined = append(combined, row)
+		}
+	}
+
+	return combined
+}
+
+// Lists each ticker of the combined results once, with the screens it
+// passed in the order of the job. Tickers keep the order they first appear
+// in
+func screenMembership(combined [][]string) ([][]string, error) {
+	header := combined[0]
+	screen := indexOf(header, screenNameColumn)
+	if screen < 0 {
+		return nil, fmt.Errorf("combined results have no %v column", screenNameColumn)
+	}
+	ticker := indexOf(header, tickerColumn)
+	if ticker < 0 {
+		return nil, fmt.Errorf("combined results have no %v column", tickerColumn)
+	}
+	company := indexOf(header, "Company Name")
+
+	membership := [][]string{{tickerColumn}}
+	if company >= 0 {
+		membership[0] = append(membership[0], "Company Name")
+	}
+	membership[0] = append(membership[0], screensColumn, screenCountColumn)
+
+	tickers := []string{}
+	passed := map[string][]string{}
+	companies := map[string]string{}
+	for _, row := range combined[1:] {
+		t := row[ticker]
+		if _, ok := passed[t]; !ok {
+			tickers = append(tickers, t)
+		}
+		if indexOf(passed[t], row[screen]) < 0 {
+			passed[t] = append(passed[t], row[screen])
+		}
+		if company >= 0 && companies[t] == "" {
+			companies[t] = row[company]
+		}
+	}
+
+	for _, t := range tickers {
+		row := []string{t}
+		if company >= 0 {
+			row = append(row, companies[t])
+		}
+		row = append(row, strings.Join(passed[t], ";"), strconv.Itoa(len(passed[t])))
+		membership = append(membership, row)
+	}
+
+	return membership, nil
+}
@@ -0,0 +1,112 @@
+package stockscreener
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNamedScreens(t *testing.T) {
+	screens, err := parseNamedScreens([]map[string]interface{}{
+		{"screens": []interface{}{
+			map[string]interface{}{"name": "value", "criteria": []interface{}{"zacks_rank <= 2"}},
+			map[string]interface{}{"name": "bull", "id": "momentum_score", "value": "A", "operator": "="},
+			map[string]interface{}{"name": "legacy", "parameters": []interface{}{
+				map[string]interface{}{"id": "zacks_rank", "value": "1", "operator": "<="},
+			}},
+		}},
+		{"max_rows": 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []namedScreen{
+		{"value", []map[string]interface{}{{"criteria": []interface{}{"zacks_rank <= 2"}}}},
+		{"bull", []map[string]interface{}{{"id": "momentum_score", "value": "A", "operator": "="}}},
+		{"legacy", []map[string]interface{}{{"id": "zacks_rank", "value": "1", "operator": "<="}}},
+	}
+	if !reflect.DeepEqual(screens, expected) {
+		t.Fatalf("expected %v, got %v", expected, screens)
+	}
+
+	parameters := []map[string]interface{}{{"criteria": "zacks_rank <= 2"}}
+	screens, err = parseNamedScreens(parameters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(screens) != 1 || screens[0].Name != "" || !reflect.DeepEqual(screens[0].Parameters, parameters) {
+		t.Fatalf("expected one unnamed screen, got %v", screens)
+	}
+}
+
+func TestParseNamedScreensInvalid(t *testing.T) {
+	cases := map[string][]map[string]interface{}{
+		"not a list":  {{"screens": "value"}},
+		"no name":     {{"screens": []interface{}{map[string]interface{}{"criteria": "zacks_rank <= 2"}}}},
+		"no criteria": {{"screens": []interface{}{map[string]interface{}{"name": "value"}}}},
+		"duplicate name": {{"screens": []interface{}{
+			map[string]interface{}{"name": "value", "criteria": "zacks_rank <= 2"},
+			map[string]interface{}{"name": "value", "criteria": "zacks_rank <= 1"},
+		}}},
+		"criteria outside screens": {
+			{"screens": []interface{}{map[string]interface{}{"name": "value", "criteria": "zacks_rank <= 2"}}},
+			{"criteria": "zacks_rank <= 1"},
+		},
+		"result option inside a screen": {{"screens": []interface{}{
+			map[string]interface{}{"name": "value", "criteria": "zacks_rank <= 2", "max_rows": 5},
+		}}},
+	}
+
+	for name, parameters := range cases {
+		_, err := parseNamedScreens(parameters)
+		if err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
+
+func TestScreenMembership(t *testing.T) {
+	combined := combineScreens([]string{"value", "growth"}, [][][]string{
+		{
+			{"Company Name", "Ticker", "Zacks Rank"},
+			{"Apple Inc.", "AAPL", "3"},
+			{"Caterpillar Inc.", "CAT", "2"},
+		},
+		{
+			{"Ticker", "Company Name", "PEG Ratio"},
+			{"CAT", "Caterpillar Inc.", "1.58"},
+			{"NVDA", "NVIDIA Corporation", "1.25"},
+		},
+	})
+
+	expected := [][]string{
+		{"screen_name", "Company Name", "Ticker", "Zacks Rank", "PEG Ratio"},
+		{"value", "Apple Inc.", "AAPL", "3", ""},
+		{"value", "Caterpillar Inc.", "CAT", "2", ""},
+		{"growth", "Caterpillar Inc.", "CAT", "", "1.58"},
+		{"growth", "NVIDIA Corporation", "NVDA", "", "1.25"},
+	}
+	if !reflect.DeepEqual(combined, expected) {
+		t.Fatalf("expected %q, got %q", expected, combined)
+	}
+
+	expected = [][]string{
+		{"Ticker", "Company Name", "screens", "screen_count"},
+		{"AAPL", "Apple Inc.", "value", "1"},
+		{"CAT", "Caterpillar Inc.", "value;growth", "2"},
+		{"NVDA", "NVIDIA Corporation", "growth", "1"},
+	}
+	membership, err := screenMembership(combined)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(membership, expected) {
+		t.Fatalf("expected %q, got %q", expected, membership)
+	}
+
+	// An export without tickers can't be tagged
+	_, err = screenMembership([][]string{{screenNameColumn, "Company Name"}, {"value", "Apple Inc."}})
+	if err == nil {
+		t.Fatal("expected an error without a Ticker column")
+	}
+}
@@ -0,0 +1,86 @@
+package stockscreener
+
+import (
+	"bytes"
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTestQuery(config []map[string]interface{}) (map[string][]string, error) {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	err := WriteQuery(writer, config, 1)
+	if err != nil {
+		return nil, err
+	}
+	writer.Close()
+
+	form, err := multipart.NewReader(body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		return nil, err
+	}
+	return form.Value, nil
+}
+
+func TestWriteQuery(t *testing.T) {
+	form, err := writeTestQuery([]map[string]interface{}{
+		{"id": "zacks_rank", "value": 1, "operator": "<="},
+		{"id": "value_score", "value": "B", "operator": ">="},
+		{"id": "optionable", "value": "YES", "operator": "EQUAL"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"operator[]":    {"7", "12", "9"},
+		"value[]":       {"1", "B", "YES"},
+		"p_items[]":     {"15005", "15030", "11015"},
+		"p_item_name[]": {"Zacks Rank", "Value Score", "Optionable"},
+		"p_item_key[]":  {"0", "2", "12"},
+	}
+	for field, values := range expected {
+		if !reflect.DeepEqual(form[field], values) {
+			t.Fatalf("unexpected %v: %v", field, form[field])
+		}
+	}
+}
+
+func TestWriteQueryInvalid(t *testing.T) {
+	cases := map[string]map[string]interface{}{
+		"unknown criterion": {"id": "shoe_size", "value": "10", "operator": ">="},
+		"unknown operator":  {"id": "market_cap", "value": "1000", "operator": ">"},
+		"invalid grade":     {"id": "vgm_score", "value": "E", "operator": "="},
+		"rank out of range": {"id": "zacks_rank", "value": "6", "operator": "<="},
+	}
+
+	for name, item := range cases {
+		_, err := writeTestQuery([]map[string]interface{}{item})
+		if err == nil {
+			t.Fatalf("%v: expected an error", name)
+		}
+	}
+}
+
+func TestWriteQueryStoredScreens(t *testing.T) {
+	for _, item := range []map[string]interface{}{
+		{"saved_screen": "My Value Screen"},
+		{"predefined_screen": "Bull of the Day"},
+	} {
+		_, err := writeTestQuery([]map[string]interface{}{item})
+		if err == nil || !strings.Contains(err.Error(), "isn't supported") {
+			t.Fatalf("expected %v to be unsupported, got %v", item, err)
+		}
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	for _, c := range Catalog {
+		if c.ID == "" || c.Code == "" || c.Key == "" || c.Name == "" || c.Category == "" || c.Type == "" {
+			t.Fatalf("incomplete criterion: %+v", c)
+		}
+	}
+}
@@ -10,72 +10,240 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/iamburbo/zacks-scraper/archive"
 	"github.com/iamburbo/zacks-scraper/config"
+	"github.com/iamburbo/zacks-scraper/output"
 	"github.com/iamburbo/zacks-scraper/util"
+	"github.com/iamburbo/zacks-scraper/zacks"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 )
 
-func RunStockScreener(job *config.ScrapeJob, client *http.Client) error {
+func RunStockScreener(job *config.ScrapeJob, client *http.Client, run *output.Run) error {
 	if job.JobType != "stock_screener" {
 		return fmt.Errorf("invalid job type: %v", job)
 	}
 
-	// Send requests
-	prefix := "an error occured while"
-	parsedStockScreenerPage, err := getStockScreenerPage(client)
+	opts, err := parseResultOptions(job.Parameters)
 	if err != nil {
-		return fmt.Errorf("%v fetching stock screener page: %w", prefix, err)
+		return err
 	}
 
-	err = getScreenerFromApi(client, parsedStockScreenerPage)
+	screens, err := parseNamedScreens(job.Parameters)
 	if err != nil {
-		return fmt.Errorf("%v fetching screener API page: %w", prefix, err)
+		return err
+	}
+
+	// Every screen runs on the same screener session, opened on the first
+	// request so replays don't need one
+	session := &screenerSession{client: client}
+
+	names := []string{}
+	results := [][][]string{}
+	var exchange *archive.Exchange
+	for _, screen := range screens {
+		data, ex, err := fetchScreen(job, run, session, screen, opts)
+		if errors.Is(err, output.ErrNotArchived) {
+			log.Printf("skipping stock screener: %v", err)
+			return nil
+		}
+		if err != nil {
+			if screen.Name != "" {
+				return fmt.Errorf("screen %v: %w", screen.Name, err)
+			}
+			return err
+		}
+
+		names = append(names, screen.Name)
+		results = append(results, data)
+		if exchange == nil {
+			exchange = ex
+		}
+	}
+
+	rules := []output.Rule{
+		output.SymbolFormat("Ticker"),
+		output.UniqueSymbol("Ticker"),
+	}
+
+	// A single unnamed screen is written as Zacks exports it
+	if len(screens) == 1 && screens[0].Name == "" {
+		table := output.TableFromRecords("stock_screener", results[0])
+		err = run.WriteTable(job, output.Partition{
+			Date:         exchange.FetchedAt,
+			Ext:          "csv",
+			FlatTemplate: "{timestamp}.{ext}",
+			Endpoint:     zacks.ScreenerExportURL(),
+			ScrapedAt:    exchange.FetchedAt,
+			Rules:        rules,
+		}, table)
+		if err != nil {
+			return err
+		}
+
+		return writeDiff(job, run, opts, table, exchange)
 	}
 
-	err = resetStockScreenerParam(client)
+	// Tickers passing several screens appear once per screen
+	combined := output.TableFromRecords("stock_screener", combineScreens(names, results))
+	err = run.WriteTable(job, output.Partition{
+		Date:         exchange.FetchedAt,
+		Ext:          "csv",
+		FlatTemplate: "{timestamp}.{ext}",
+		Endpoint:     zacks.ScreenerExportURL(),
+		ScrapedAt:    exchange.FetchedAt,
+		Rules:        []output.Rule{output.SymbolFormat("Ticker")},
+	}, combined)
 	if err != nil {
-		return fmt.Errorf("%v resetting query params: %w", prefix, err)
+		return err
 	}
 
-	err = queryScreenerApi(client, job.Parameters)
+	// Built from the rows written, so it follows the job's where filter
+	membership, err := screenMembership(combined.Records())
 	if err != nil {
-		return fmt.Errorf("%v sending query: %w", prefix, err)
+		return err
 	}
+	err = run.WriteTable(job, output.Partition{
+		Dataset:       "stock_screener_membership",
+		Date:          exchange.FetchedAt,
+		Ext:           "csv",
+		FlatTemplate:  "{timestamp}_membership.{ext}",
+		Endpoint:      zacks.ScreenerExportURL(),
+		ScrapedAt:     exchange.FetchedAt,
+		Rules:         rules,
+		SkipTransform: true,
+	}, output.TableFromRecords("stock_screener_membership", membership))
+	if err != nil {
+		return err
+	}
+
+	return writeDiff(job, run, opts, combined, exchange)
+}
+
+// Runs a screen once per result view and returns its merged, sorted and
+// truncated results
+func fetchScreen(job *config.ScrapeJob, run *output.Run, session *screenerSession, screen namedScreen, opts *resultOptions) ([][]string, *archive.Exchange, error) {
+	views := [][][]string{}
+	var exchange *archive.Exchange
+	for _, view := range opts.Views {
+		// Archived under the screen's name and, once views are configured,
+		// the view's
+		tabs := []string{}
+		if screen.Name != "" {
+			tabs = append(tabs, screen.Name)
+		}
+		if opts.Configured {
+			tabs = append(tabs, view)
+		}
+
+		body, ex, err := run.Fetch(job, archive.Entry{
+			Kind: archive.KindScreenerCSV,
+			Tab:  strings.Join(tabs, "_"),
+		}, func() ([]byte, *archive.Exchange, error) {
+			return session.runScreen(screen.Parameters, resultViews[view])
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		records, err := parseScreenerCSV(body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("an error occured while parsing %v view: %w", view, err)
+		}
+		views = append(views, records)
 
-	data, err := downloadData(client, parsedStockScreenerPage)
+		if exchange == nil {
+			exchange = ex
+		}
+	}
+
+	data, err := mergeViews(views)
 	if err != nil {
-		return fmt.Errorf("%v resetting query params: %w", prefix, err)
+		return nil, nil, err
 	}
 
-	// Write data to output directory
-	fileName := time.Now().Format("20060102150405") + ".csv"
-	f, err := os.Create(filepath.Join(job.OutDir, fileName))
+	data, err = applyResultOptions(data, opts)
 	if err != nil {
-		log.Fatalf("error creating output file: %v", err)
+		return nil, nil, err
 	}
-	w := csv.NewWriter(f)
-	defer w.Flush()
+	return data, exchange, nil
+}
 
-	for _, record := range data {
-		if err = w.Write(record); err != nil {
-			log.Fatalln("error writing record to file", err)
-		}
+// The screener pages a client has gone through, which authorize its
+// requests to the screener API
+type screenerSession struct {
+	client *http.Client
+	page   *parsedStockScreenerHomePage
+}
+
+func (s *screenerSession) open() error {
+	if s.page != nil {
+		return nil
 	}
 
+	prefix := "an error occured while"
+	page, err := getStockScreenerPage(s.client)
+	if err != nil {
+		return fmt.Errorf("%v fetching stock screener page: %w", prefix, err)
+	}
+
+	err = getScreenerFromApi(s.client, page)
+	if err != nil {
+		return fmt.Errorf("%v fetching screener API page: %w", prefix, err)
+	}
+
+	s.page = page
 	return nil
 }
 
+// Runs the screener query through the same sequence of requests as the
+// browser and downloads the results as CSV
+func (s *screenerSession) runScreen(parameters []map[string]interface{}, tabID int) ([]byte, *archive.Exchange, error) {
+	prefix := "an error occured while"
+	err := s.open()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = resetStockScreenerParam(s.client)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v resetting query params: %w", prefix, err)
+	}
+
+	form, err := queryScreenerApi(s.client, parameters, tabID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v sending query: %w", prefix, err)
+	}
+
+	body, exchange, err := downloadData(s.client, s.page)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v downloading data: %w", prefix, err)
+	}
+
+	// The export is of the query posted before it
+	exchange.Form = form
+	return body, exchange, nil
+}
+
 type parsedStockScreenerHomePage struct {
 	CKey string
 }
 
 func parseStockScreenerHomePage(body string) (*parsedStockScreenerHomePage, error) {
-	substr := util.FindStringInBetween(`<iframe style="" title="Stock Screener " id="screenerContent" src="`, `" scrolling="yes" allowfullscreen></iframe>`, body)
-	parsed, err := url.Parse(substr)
+	iframe := util.FindElement(util.ParseFragment(body), func(n *html.Node) bool {
+		id, _ := util.Attr(n, "id")
+		return n.DataAtom == atom.Iframe && id == "screenerContent"
+	})
+	if iframe == nil {
+		return nil, errors.New("no screener iframe found")
+	}
+
+	src, _ := util.Attr(iframe, "src")
+	parsed, err := url.Parse(src)
 	if err != nil {
 		return nil, err
 	}
@@ -91,7 +259,7 @@ func parseStockScreenerHomePage(body string) (*parsedStockScreenerHomePage, erro
 
 // fetch screener page from frontend to set important cookies
 func getStockScreenerPage(client *http.Client) (*parsedStockScreenerHomePage, error) {
-	screenerUrl, err := url.Parse("https://www.zacks.com/screening/stock-screener?icid=home-home-nav_tracking-zcom-main_menu_wrapper-stock_screener")
+	screenerUrl, err := url.Parse(zacks.StockScreenerURL() + "?icid=home-home-nav_tracking-zcom-main_menu_wrapper-stock_screener")
 	if err != nil {
 		return nil, err
 	}
@@ -129,7 +297,7 @@ func getStockScreenerPage(client *http.Client) (*parsedStockScreenerHomePage, er
 
 // Retrieves screener prompt homepage from backend API. Necessary to authorize future requests
 func getScreenerFromApi(client *http.Client, parsed *parsedStockScreenerHomePage) error {
-	screenerUrl, err := url.Parse(`https://screener-api.zacks.com/`)
+	screenerUrl, err := url.Parse(zacks.ScreenerAPIURL())
 	if err != nil {
 		return err
 	}
@@ -149,7 +317,7 @@ func getScreenerFromApi(client *http.Client, parsed *parsedStockScreenerHomePage
 	req.Header.Set("user-agent", `Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/106.0.0.0 Safari/537.36`)
 	req.Header.Set("accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.9")
 
-	cookieUrl, _ := url.Parse("https://www.zacks.com")
+	cookieUrl, _ := url.Parse(zacks.WWW)
 	for _, c := range client.Jar.Cookies(cookieUrl) {
 		req.AddCookie(c)
 	}
@@ -168,11 +336,12 @@ func getScreenerFromApi(client *http.Client, parsed *parsedStockScreenerHomePage
 	}
 }
 
-// Sends query to stock screener api via multipart form data
-func queryScreenerApi(client *http.Client, parameters []map[string]interface{}) error {
-	screenApiUrl, err := url.Parse("https://screener-api.zacks.com/getrunscreendata.php")
+// Sends query to stock screener api via multipart form data. Returns the
+// fields of the form sent
+func queryScreenerApi(client *http.Client, parameters []map[string]interface{}, tabID int) (map[string][]string, error) {
+	screenApiUrl, err := url.Parse(zacks.RunScreenURL())
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Query body writer
@@ -182,14 +351,25 @@ func queryScreenerApi(client *http.Client, parameters []map[string]interface{})
 	writer.SetBoundary(boundary)
 
 	// Queries
-	err = WriteQuery(writer, parameters)
+	err = WriteQuery(writer, parameters, tabID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// Writes the closing boundary
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	form, err := multipart.NewReader(bytes.NewReader(body.Bytes()), boundary).ReadForm(int64(body.Len()))
+	if err != nil {
+		return nil, err
 	}
 
 	req, err := http.NewRequest("POST", screenApiUrl.String(), body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	req.Header.Set("sec-ch-ua", `"Chromium";v="106", "Google Chrome";v="106", "Not;A=Brand";v="99"`)
@@ -199,14 +379,14 @@ func queryScreenerApi(client *http.Client, parameters []map[string]interface{})
 	req.Header.Set("sec-ch-ua-mobile", "?0")
 	req.Header.Set("user-agent", `Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/106.0.0.0 Safari/537.36`)
 	req.Header.Set("sec-ch-ua-platform", `"macOS"`)
-	req.Header.Set("origin", "https://screener-api.zacks.com")
+	req.Header.Set("origin", zacks.ScreenerAPI)
 	req.Header.Set("sec-fetch-site", "same-origin")
 	req.Header.Set("sec-fetch-mode", "cors")
 	req.Header.Set("sec-fetch-dest", "empty")
-	req.Header.Set("referer", "https://screener-api.zacks.com/?scr_type=stock&c_id=zacks&c_key=0675466c5b74cfac34f6be7dc37d4fe6a008e212e2ef73bdcd7e9f1f9a9bd377&ecv=4MTNzETOygTM&ref=screening")
+	req.Header.Set("referer", zacks.ScreenerAPIURL()+"?scr_type=stock&c_id=zacks&c_key=0675466c5b74cfac34f6be7dc37d4fe6a008e212e2ef73bdcd7e9f1f9a9bd377&ecv=4MTNzETOygTM&ref=screening")
 
 	// Cookies won't auto add since login was from a different host
-	cookieUrl, _ := url.Parse("https://www.zacks.com")
+	cookieUrl, _ := url.Parse(zacks.WWW)
 	for _, c := range client.Jar.Cookies(cookieUrl) {
 		req.AddCookie(c)
 	}
@@ -217,21 +397,21 @@ func queryScreenerApi(client *http.Client, parameters []map[string]interface{})
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	switch resp.StatusCode {
 	case 200:
-		return nil
+		return form.Value, nil
 	default:
-		return errors.New("Status code: " + strconv.Itoa(resp.StatusCode))
+		return nil, errors.New("Status code: " + strconv.Itoa(resp.StatusCode))
 	}
 }
 
 // Called by browser - reset params just in case
 func resetStockScreenerParam(client *http.Client) error {
-	resetParamUrl, err := url.Parse(`https://screener-api.zacks.com/reset_param.php`)
+	resetParamUrl, err := url.Parse(zacks.ResetParamURL())
 	if err != nil {
 		return err
 	}
@@ -265,15 +445,15 @@ func resetStockScreenerParam(client *http.Client) error {
 }
 
 // Downloads query in CSV format
-func downloadData(client *http.Client, parsed *parsedStockScreenerHomePage) ([][]string, error) {
-	downloadUrl, err := url.Parse(`https://screener-api.zacks.com/export.php`)
+func downloadData(client *http.Client, parsed *parsedStockScreenerHomePage) ([]byte, *archive.Exchange, error) {
+	downloadUrl, err := url.Parse(zacks.ScreenerExportURL())
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	req, err := http.NewRequest("GET", downloadUrl.String(), nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	req.Header.Set("sec-ch-ua", `"Chromium";v="106", "Google Chrome";v="106", "Not;A=Brand";v="99"`)
 	req.Header.Set("sec-ch-ua-mobile", "?0")
@@ -285,11 +465,11 @@ func downloadData(client *http.Client, parsed *parsedStockScreenerHomePage) ([][
 	req.Header.Set("sec-fetch-mode", "navigate")
 	req.Header.Set("sec-fetch-user", "?1")
 	req.Header.Set("sec-fetch-dest", "iframe")
-	req.Header.Set("referer", "https://screener-api.zacks.com/?scr_type=stock&c_id=zacks&c_key="+parsed.CKey+"&ecv=4MTNzETOygTM&ref=screening")
+	req.Header.Set("referer", zacks.ScreenerAPIURL()+"?scr_type=stock&c_id=zacks&c_key="+parsed.CKey+"&ecv=4MTNzETOygTM&ref=screening")
 	req.Header.Set("accept-language", "en-US,en;q=0.9")
 
 	// Cookies won't auto add since login was from a different host
-	cookieUrl, _ := url.Parse("https://www.zacks.com")
+	cookieUrl, _ := url.Parse(zacks.WWW)
 	for _, c := range client.Jar.Cookies(cookieUrl) {
 		req.AddCookie(c)
 	}
@@ -298,21 +478,26 @@ func downloadData(client *http.Client, parsed *parsedStockScreenerHomePage) ([][
 		Value: "edit_criteria",
 	})
 
+	fetchedAt := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
 	switch resp.StatusCode {
 	case 200:
-		reader := csv.NewReader(resp.Body)
-		data, err := reader.ReadAll()
+		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		return data, nil
+		return body, archive.NewExchange(req, nil, resp, fetchedAt), nil
 	default:
-		return nil, errors.New("Status code " + strconv.Itoa(resp.StatusCode))
+		return nil, nil, errors.New("Status code " + strconv.Itoa(resp.StatusCode))
 	}
 }
+
+func parseScreenerCSV(body []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(body))
+	return reader.ReadAll()
+}
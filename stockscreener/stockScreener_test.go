@@ -1,36 +1,244 @@
 package stockscreener
 
 import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/iamburbo/zacks-scraper/archive"
 	"github.com/iamburbo/zacks-scraper/config"
-	"github.com/iamburbo/zacks-scraper/util"
+	"github.com/iamburbo/zacks-scraper/output"
 	"github.com/iamburbo/zacks-scraper/zacks"
+	"github.com/iamburbo/zacks-scraper/zackstest"
 )
 
 func TestRunScreen(t *testing.T) {
-	cfg, err := config.LoadConfigFile("../config.yml")
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &config.ScrapeJob{
+		JobType: "stock_screener",
+		OutDir:  t.TempDir(),
+		Parameters: []map[string]interface{}{
+			{"id": "zacks_rank", "value": "1", "operator": ">="},
+		},
+	}
+
+	err = RunStockScreener(job, client, output.NewRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(job.OutDir, "*.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %v", len(files))
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) < 2 || records[0][1] != "Ticker" {
+		t.Fatalf("unexpected records: %v", records)
+	}
+
+	requests := server.RequestsTo("/getrunscreendata.php")
+	if len(requests) != 1 || requests[0].Form.Get("p_items[]") != "15005" {
+		t.Fatalf("unexpected screen requests: %+v", requests)
+	}
+}
+
+func TestRunScreenArchivesQuery(t *testing.T) {
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &config.ScrapeJob{
+		JobType:    "stock_screener",
+		OutDir:     t.TempDir(),
+		ArchiveRaw: true,
+		Parameters: []map[string]interface{}{
+			{"id": "zacks_rank", "value": "1", "operator": ">="},
+		},
+	}
+
+	err = RunStockScreener(job, client, output.NewRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := archive.Open(output.ArchiveDir(job)).Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 archived response, got %+v", entries)
+	}
+
+	form := entries[0].Form
+	if !reflect.DeepEqual(form["p_items[]"], []string{"15005"}) || !reflect.DeepEqual(form["tab_id"], []string{"1"}) {
+		t.Fatalf("expected the query form, got %+v", form)
+	}
+}
+
+func TestRunScreenViews(t *testing.T) {
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &config.ScrapeJob{
+		JobType: "stock_screener",
+		OutDir:  t.TempDir(),
+		Parameters: []map[string]interface{}{
+			{"id": "zacks_rank", "value": "1", "operator": ">="},
+			{"views": []interface{}{"default", "valuation"}},
+			{"sort": "PEG Ratio"},
+			{"max_rows": 2},
+		},
+	}
+
+	err = RunStockScreener(job, client, output.NewRun())
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	client := util.GetTestClient()
+	files, err := filepath.Glob(filepath.Join(job.OutDir, "*.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %v", len(files))
+	}
 
-	err = zacks.LogIn(client, cfg)
+	f, err := os.Open(files[0])
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer f.Close()
 
-	var job *config.ScrapeJob = nil
-	for _, j := range cfg.Jobs {
-		if j.JobType == "stock_screener" {
-			job = &j
-			break
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %v", records)
+	}
+
+	ticker, peg := indexOf(records[0], "Ticker"), indexOf(records[0], "PEG Ratio")
+	if peg < 0 || indexOf(records[0], "Zacks Rank") < 0 {
+		t.Fatalf("expected columns of both views, got %v", records[0])
+	}
+	if records[1][ticker] != "NVDA" || records[2][ticker] != "CAT" {
+		t.Fatalf("expected rows sorted by PEG Ratio, got %v", records[1:])
+	}
+
+	tabs := []string{}
+	for _, r := range server.RequestsTo("/getrunscreendata.php") {
+		tabs = append(tabs, r.Form.Get("tab_id"))
+		if r.Form.Get("p_items[]") != "15005" {
+			t.Errorf("expected the criteria with every view, got %v", r.Form)
 		}
 	}
+	if len(tabs) != 2 || tabs[0] != "1" || tabs[1] != "3" {
+		t.Fatalf("expected the default and valuation views, got %v", tabs)
+	}
+}
+
+func TestRunNamedScreens(t *testing.T) {
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &config.ScrapeJob{
+		JobType: "stock_screener",
+		OutDir:  t.TempDir(),
+		Parameters: []map[string]interface{}{
+			{"screens": []interface{}{
+				map[string]interface{}{"name": "strong_buys", "criteria": "zacks_rank <= 1"},
+				map[string]interface{}{"name": "value", "criteria": "value_score >= B"},
+			}},
+		},
+	}
+
+	err = RunStockScreener(job, client, output.NewRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	combined := readScreenerOutput(t, filepath.Join(job.OutDir, "[0-9]*[0-9].csv"))
+	if combined[0][0] != "screen_name" || len(combined) != 7 {
+		t.Fatalf("expected both screens' rows, got %v", combined)
+	}
+	if combined[1][0] != "strong_buys" || combined[6][0] != "value" {
+		t.Fatalf("expected rows in the order of the screens, got %v", combined)
+	}
+
+	membership := readScreenerOutput(t, filepath.Join(job.OutDir, "*_membership.csv"))
+	if len(membership) != 4 || membership[1][2] != "strong_buys;value" || membership[1][3] != "2" {
+		t.Fatalf("unexpected membership: %v", membership)
+	}
+
+	// One screener session for both screens
+	if n := len(server.RequestsTo("/screening/stock-screener")); n != 1 {
+		t.Fatalf("expected the screener page to be fetched once, got %v", n)
+	}
+	if n := len(server.RequestsTo("/getrunscreendata.php")); n != 2 {
+		t.Fatalf("expected 2 screens to run, got %v", n)
+	}
+}
+
+func readScreenerOutput(t *testing.T, pattern string) [][]string {
+	t.Helper()
+
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file matching %v, got %v", pattern, files)
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
 
-	err = RunStockScreener(job, client)
+	records, err := csv.NewReader(f).ReadAll()
 	if err != nil {
 		t.Fatal(err)
 	}
+	return records
 }
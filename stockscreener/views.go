@@ -0,0 +1,218 @@
+package stockscreener
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/iamburbo/zacks-scraper/output"
+)
+
+// Result views of the screener, by their tab_id. Each view exports a
+// different set of columns. Only the views with an export fixture in
+// zackstest are listed: the baseline's default view and the valuation
+// view. Add others together with a capture of their export
+var resultViews = map[string]int{
+	"default":   1,
+	"valuation": 3,
+}
+
+// Column views are merged on
+const tickerColumn = "Ticker"
+
+// Job parameters shaping the results, as opposed to criteria
+var resultParameters = []string{"view", "views", "sort", "sort_direction", "max_rows", "diff"}
+
+type resultOptions struct {
+	Views      []string // at least one
+	Configured bool     // views were set in the config
+	Sort       string   // column to sort by, "" keeps the order Zacks returns
+	Descending bool
+	MaxRows    int  // 0 for all rows
+	Diff       bool // compare the results with the job's previous run
+}
+
+func isResultParameter(item map[string]interface{}) bool {
+	for _, p := range resultParameters {
+		if _, ok := item[p]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Parses the result options from a job's parameters
+func parseResultOptions(parameters []map[string]interface{}) (*resultOptions, error) {
+	opts := &resultOptions{}
+
+	for _, p := range parameters {
+		if v, ok := p["view"]; ok {
+			opts.Views = append(opts.Views, fmt.Sprint(v))
+		}
+
+		if v, ok := p["views"]; ok {
+			views, ok := v.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("views must be a list, got %v", v)
+			}
+			for _, view := range views {
+				opts.Views = append(opts.Views, fmt.Sprint(view))
+			}
+		}
+
+		if v, ok := p["sort"]; ok {
+			opts.Sort = fmt.Sprint(v)
+		}
+
+		if v, ok := p["sort_direction"]; ok {
+			switch strings.ToLower(fmt.Sprint(v)) {
+			case "asc":
+				opts.Descending = false
+			case "desc":
+				opts.Descending = true
+			default:
+				return nil, fmt.Errorf("sort_direction must be asc or desc, got %v", v)
+			}
+		}
+
+		if v, ok := p["max_rows"]; ok {
+			n, err := strconv.Atoi(fmt.Sprint(v))
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("max_rows must be a positive number, got %v", v)
+			}
+			opts.MaxRows = n
+		}
+
+		if v, ok := p["diff"]; ok {
+			diff, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("diff must be true or false, got %v", v)
+			}
+			opts.Diff = diff
+		}
+	}
+
+	for _, view := range opts.Views {
+		if _, ok := resultViews[view]; !ok {
+			return nil, fmt.Errorf("unknown result view: %v", view)
+		}
+	}
+
+	opts.Configured = len(opts.Views) > 0
+	if !opts.Configured {
+		opts.Views = []string{"default"}
+	}
+
+	return opts, nil
+}
+
+// Joins the exports of several views on their tickers. Rows and columns
+// of the first view come first, later views add the columns and tickers it
+// lacks. Cells a view doesn't have are left empty
+func mergeViews(views [][][]string) ([][]string, error) {
+	if len(views) == 1 {
+		return views[0], nil
+	}
+
+	header := []string{}
+	position := map[string]int{} // of each column in header
+	rows := [][]string{}
+	rowByTicker := map[string]int{}
+
+	for v, records := range views {
+		if len(records) == 0 {
+			continue
+		}
+
+		ticker := indexOf(records[0], tickerColumn)
+		if ticker < 0 {
+			return nil, fmt.Errorf("view %v has no %v column", v, tickerColumn)
+		}
+
+		for _, c := range records[0] {
+			if _, ok := position[c]; !ok {
+				position[c] = len(header)
+				header = append(header, c)
+			}
+		}
+
+		for _, record := range records[1:] {
+			r, ok := rowByTicker[record[ticker]]
+			if !ok || v == 0 {
+				rows = append(rows, nil)
+				r = len(rows) - 1
+				rowByTicker[record[ticker]] = r
+			}
+			for len(rows[r]) < len(header) {
+				rows[r] = append(rows[r], "")
+			}
+
+			// Columns shared with earlier views keep their values
+			for i, c := range records[0] {
+				if rows[r][position[c]] == "" {
+					rows[r][position[c]] = record[i]
+				}
+			}
+		}
+	}
+
+	for r := range rows {
+		for len(rows[r]) < len(header) {
+			rows[r] = append(rows[r], "")
+		}
+	}
+
+	return append([][]string{header}, rows...), nil
+}
+
+// Sorts the rows by a column and keeps at most maxRows of them. Numbers
+// are compared as numbers, and empty cells sort last
+func applyResultOptions(records [][]string, opts *resultOptions) ([][]string, error) {
+	if len(records) == 0 {
+		return records, nil
+	}
+	header, rows := records[0], records[1:]
+
+	if opts.Sort != "" {
+		c := indexOf(header, opts.Sort)
+		if c < 0 {
+			return nil, fmt.Errorf("no column %q to sort by, columns: %q", opts.Sort, header)
+		}
+
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := rows[i][c], rows[j][c]
+			if a == "" || b == "" {
+				return a != "" && b == ""
+			}
+			if opts.Descending {
+				a, b = b, a
+			}
+			return lessCell(a, b)
+		})
+	}
+
+	if opts.MaxRows > 0 && len(rows) > opts.MaxRows {
+		rows = rows[:opts.MaxRows]
+	}
+
+	return append([][]string{header}, rows...), nil
+}
+
+func lessCell(a, b string) bool {
+	x, errA := output.ParseNumber(a)
+	y, errB := output.ParseNumber(b)
+	if errA == nil && errB == nil && x != nil && y != nil {
+		return *x < *y
+	}
+	return a < b
+}
+
+func indexOf(header []string, column string) int {
+	for i, c := range header {
+		if c == column {
+			return i
+		}
+	}
+	return -1
+}
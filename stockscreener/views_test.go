@@ -0,0 +1,118 @@
+package stockscreener
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseResultOptions(t *testing.T) {
+	opts, err := parseResultOptions([]map[string]interface{}{
+		{"id": "zacks_rank", "value": "1", "operator": ">="},
+		{"views": []interface{}{"default", "valuation"}},
+		{"sort": "PEG Ratio"},
+		{"sort_direction": "DESC"},
+		{"max_rows": 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &resultOptions{
+		Views:      []string{"default", "valuation"},
+		Configured: true,
+		Sort:       "PEG Ratio",
+		Descending: true,
+		MaxRows:    2,
+	}
+	if !reflect.DeepEqual(opts, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, opts)
+	}
+
+	opts, err = parseResultOptions(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Configured || !reflect.DeepEqual(opts.Views, []string{"default"}) {
+		t.Fatalf("expected the default view, got %+v", opts)
+	}
+
+	for _, p := range []map[string]interface{}{
+		{"view": "fundamentals"},
+		{"view": "growth"}, // no recorded export
+		{"views": "valuation"},
+		{"sort_direction": "up"},
+		{"max_rows": 0},
+	} {
+		_, err := parseResultOptions([]map[string]interface{}{p})
+		if err == nil {
+			t.Errorf("expected an error for %v", p)
+		}
+	}
+}
+
+func TestMergeViews(t *testing.T) {
+	merged, err := mergeViews([][][]string{
+		{
+			{"Company Name", "Ticker", "Zacks Rank"},
+			{"Apple Inc.", "AAPL", "3"},
+			{"Caterpillar Inc.", "CAT", "2"},
+		},
+		{
+			{"Company Name", "Ticker", "PEG Ratio"},
+			{"Caterpillar Inc.", "CAT", "1.58"},
+			{"NVIDIA Corporation", "NVDA", "1.25"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{
+		{"Company Name", "Ticker", "Zacks Rank", "PEG Ratio"},
+		{"Apple Inc.", "AAPL", "3", ""},
+		{"Caterpillar Inc.", "CAT", "2", "1.58"},
+		{"NVIDIA Corporation", "NVDA", "", "1.25"},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Fatalf("expected %q, got %q", expected, merged)
+	}
+
+	_, err = mergeViews([][][]string{
+		{{"Ticker"}, {"AAPL"}},
+		{{"Symbol"}, {"AAPL"}},
+	})
+	if err == nil {
+		t.Fatal("expected an error for a view without tickers")
+	}
+}
+
+func TestApplyResultOptions(t *testing.T) {
+	records := [][]string{
+		{"Ticker", "Last Close", "Earnings ESP"},
+		{"AAPL", "$191.56", "+3.81%"},
+		{"NVDA", "$1,615.27", "-2.50%"},
+		{"MSFT", "$397.58", ""},
+		{"CAT", "$97.10", "+12.00%"},
+	}
+
+	// Prices and percentages sort as numbers, empty cells last
+	sorted, err := applyResultOptions(records, &resultOptions{Sort: "Earnings ESP", Descending: true, MaxRows: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tickers := []string{}
+	for _, row := range sorted[1:] {
+		tickers = append(tickers, row[0])
+	}
+	if !reflect.DeepEqual(tickers, []string{"CAT", "AAPL", "NVDA"}) {
+		t.Fatalf("unexpected order: %v", tickers)
+	}
+
+	sorted, err = applyResultOptions(records, &resultOptions{Sort: "Last Close"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sorted[1][0] != "CAT" || sorted[4][0] != "NVDA" {
+		t.Fatalf("unexpected order: %v", sorted)
+	}
+}
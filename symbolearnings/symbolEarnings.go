@@ -0,0 +1,256 @@
+package symbolearnings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/archive"
+	"github.com/iamburbo/zacks-scraper/config"
+	"github.com/iamburbo/zacks-scraper/earningscalendar"
+	"github.com/iamburbo/zacks-scraper/output"
+	"github.com/iamburbo/zacks-scraper/util"
+	"github.com/iamburbo/zacks-scraper/zacks"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Key of the earnings table in the page's obj_data script. Each row is
+// date, period ending, estimate, reported, surprise, % surprise and time
+const earningsTableKey = `"earnings_announcements_earnings_table"`
+
+// Returned for symbols Zacks has no page for
+var errUnknownSymbol = errors.New("unknown symbol")
+
+type symbolEarningsParams struct {
+	symbols []string
+}
+
+// A report date of a symbol, past or upcoming
+type symbolEarnings struct {
+	date time.Time
+	row  *earningscalendar.EarningsDataRow
+}
+
+// Fetches the earnings history and upcoming report dates of each symbol,
+// and writes them with the columns of the calendar's earnings tab and a
+// report_date column, one table per run
+func RunSymbolEarnings(job *config.ScrapeJob, client *http.Client, run *output.Run) error {
+	if job.JobType != "symbol_earnings" {
+		return fmt.Errorf("invalid job type: %v", job)
+	}
+
+	params, err := parseJobParameters(job.Parameters)
+	if err != nil {
+		return err
+	}
+
+	all := []*symbolEarnings{}
+	sources := map[string]output.Source{}
+	var queriedAt time.Time
+	for _, symbol := range params.symbols {
+		body, exchange, err := run.Fetch(job, archive.Entry{
+			Kind: archive.KindSymbolPage,
+			Tab:  symbol,
+		}, func() ([]byte, *archive.Exchange, error) {
+			return getSymbolEarningsPage(symbol, client)
+		})
+		if errors.Is(err, output.ErrNotArchived) || errors.Is(err, errUnknownSymbol) {
+			log.Printf("skipping %v earnings: %v", symbol, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		earnings, err := parseSymbolEarnings(symbol, body)
+		if err != nil {
+			log.Printf("skipping %v earnings: %v", symbol, err)
+			continue
+		}
+
+		all = append(all, earnings...)
+		if queriedAt.IsZero() {
+			queriedAt = exchange.FetchedAt
+		}
+		sources[symbol] = output.Source{
+			Endpoint:  exchange.URL,
+			ScrapedAt: exchange.FetchedAt,
+		}
+	}
+
+	if len(all) == 0 {
+		return nil
+	}
+
+	// By report date, and in watchlist order on the same day
+	sort.SliceStable(all, func(i, j int) bool { return all[i].date.Before(all[j].date) })
+
+	rows := []*earningscalendar.EarningsDataRow{}
+	for _, e := range all {
+		rows = append(rows, e.row)
+	}
+	table, err := output.NewTable("symbol_earnings", rows)
+	if err != nil {
+		return err
+	}
+	table.AddColumn(output.Column{Name: "report_date", Type: output.Date}, func(i int) interface{} {
+		return all[i].date
+	})
+
+	return run.WriteTable(job, output.Partition{
+		Tab:          "earnings",
+		Date:         queriedAt,
+		Ext:          "parquet",
+		FlatTemplate: "{run_time}/{timestamp}_{tab}.{ext}",
+		SourceColumn: "symbol",
+		Sources:      sources,
+		Rules: []output.Rule{
+			output.SymbolFormat("symbol"),
+			output.UniqueKey("symbol", "report_date"),
+			output.NumericEstimate("estimate"),
+		},
+	}, table)
+}
+
+// Parses the watchlist, given as symbols: [AAPL, MSFT] or symbol: AAPL
+func parseJobParameters(parameters []map[string]interface{}) (*symbolEarningsParams, error) {
+	params := &symbolEarningsParams{}
+	seen := map[string]bool{}
+
+	for _, p := range parameters {
+		for _, key := range []string{"symbol", "symbols"} {
+			values, ok := p[key]
+			if !ok {
+				continue
+			}
+			list, ok := values.([]interface{})
+			if !ok {
+				list = []interface{}{values}
+			}
+			for _, v := range list {
+				symbol := strings.ToUpper(strings.TrimSpace(fmt.Sprint(v)))
+				if symbol == "" {
+					return nil, fmt.Errorf("symbol_earnings: empty symbol in %v", values)
+				}
+				if !seen[symbol] {
+					seen[symbol] = true
+					params.symbols = append(params.symbols, symbol)
+				}
+			}
+		}
+	}
+
+	if len(params.symbols) == 0 {
+		return nil, errors.New("symbol_earnings: no symbols given")
+	}
+
+	return params, nil
+}
+
+func getSymbolEarningsPage(symbol string, client *http.Client) ([]byte, *archive.Exchange, error) {
+	req, err := http.NewRequest("GET", zacks.SymbolEarningsURL(symbol), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req.Header.Set("user-agent", `Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/106.0.0.0 Safari/537.36`)
+	req.Header.Set("accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+
+	fetchedAt := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case 200:
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return bodyBytes, archive.NewExchange(req, nil, resp, fetchedAt), nil
+	case 404:
+		return nil, nil, errUnknownSymbol
+	default:
+		return nil, nil, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+}
+
+// Parses the report dates of a symbol's earnings page. Upcoming dates have
+// not been reported yet, so their reported and surprise values are --
+func parseSymbolEarnings(symbol string, body []byte) ([]*symbolEarnings, error) {
+	page := string(body)
+
+	i := strings.Index(page, earningsTableKey)
+	if i < 0 {
+		return nil, errors.New("no earnings table found in page")
+	}
+	rest := strings.TrimLeft(page[i+len(earningsTableKey):], " \t\r\n")
+	if !strings.HasPrefix(rest, ":") {
+		return nil, errors.New("malformed earnings table")
+	}
+
+	// The decoder stops at the end of the table
+	table := [][]string{}
+	err := json.NewDecoder(strings.NewReader(rest[1:])).Decode(&table)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing earnings table: %w", err)
+	}
+
+	company := parseCompany(symbol, body)
+
+	earnings := []*symbolEarnings{}
+	for i, entry := range table {
+		if len(entry) != 7 {
+			return nil, fmt.Errorf("earnings row %v has %v cells, expected 7", i, len(entry))
+		}
+
+		date, err := time.Parse("1/2/2006", util.CellText(entry[0]))
+		if err != nil {
+			return nil, fmt.Errorf("earnings row %v: %w", i, err)
+		}
+
+		earnings = append(earnings, &symbolEarnings{
+			date: date,
+			row: &earningscalendar.EarningsDataRow{
+				Symbol:      symbol,
+				Company:     company,
+				Time:        util.CellText(entry[6]),
+				Estimate:    strings.TrimPrefix(util.CellText(entry[2]), "$"),
+				Reported:    strings.TrimPrefix(util.CellText(entry[3]), "$"),
+				Surprise:    strings.TrimPrefix(util.CellText(entry[4]), "$"),
+				PercentSurp: util.CellText(entry[5]),
+			},
+		})
+	}
+
+	return earnings, nil
+}
+
+// The page title starts with the company name, e.g.
+// "Apple Inc. (AAPL) Earnings Date and Reports"
+func parseCompany(symbol string, body []byte) string {
+	title := util.FindElement(util.ParseFragment(string(body)), func(n *html.Node) bool {
+		return n.DataAtom == atom.Title
+	})
+	if title == nil {
+		return ""
+	}
+
+	pattern := regexp.MustCompile(`^(.+?)\s*\(` + regexp.QuoteMeta(symbol) + `\)`)
+	m := pattern.FindStringSubmatch(util.Text(title))
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
@@ -0,0 +1,115 @@
+package symbolearnings
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iamburbo/zacks-scraper/config"
+	"github.com/iamburbo/zacks-scraper/output"
+	"github.com/iamburbo/zacks-scraper/zacks"
+	"github.com/iamburbo/zacks-scraper/zackstest"
+)
+
+func TestRunSymbolEarnings(t *testing.T) {
+	server := zackstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	err := zacks.LogIn(client, server.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := &config.ScrapeJob{
+		JobType:    "symbol_earnings",
+		OutDir:     t.TempDir(),
+		Layout:     output.LayoutPartitioned,
+		Provenance: true,
+		Parameters: []map[string]interface{}{
+			// ZZZZ has no page and is skipped
+			{"symbols": []interface{}{"aapl", "NKE", "ZZZZ"}},
+		},
+	}
+
+	run := output.NewRun()
+	err = RunSymbolEarnings(job, client, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every report date of the watchlist is in one file for the run
+	files, err := filepath.Glob(filepath.Join(job.OutDir, "dataset=symbol_earnings", "tab=earnings", "date=*", "*.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || !strings.Contains(files[0], "date="+run.StartedAt.Format("2006-01-02")) {
+		t.Fatalf("expected 1 file for the run, got %v", files)
+	}
+
+	rows, err := zackstest.ReadParquet(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := func(date string) int32 {
+		d, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int32(d.Unix() / 86400)
+	}
+	byDate := map[int32][]map[string]interface{}{}
+	for _, r := range rows {
+		byDate[r["report_date"].(int32)] = append(byDate[r["report_date"].(int32)], r)
+	}
+	if len(byDate) != 4 {
+		t.Fatalf("expected 4 report dates, got %+v", rows)
+	}
+
+	// Both symbols reported on the same day
+	reported := byDate[day("2024-02-01")]
+	if len(reported) != 2 || reported[0]["symbol"] != "AAPL" || reported[1]["symbol"] != "NKE" {
+		t.Fatalf("unexpected rows: %+v", reported)
+	}
+	aapl := reported[0]
+	if aapl["company"] != "Apple Inc." || aapl["estimate"] != "2.10" || aapl["reported"] != "2.18" ||
+		aapl["surprise"] != "+0.08" || aapl["percentSurp"] != "+3.81%" || aapl["time"] != "After Close" {
+		t.Fatalf("unexpected AAPL row: %+v", aapl)
+	}
+
+	// Each row names the page it was read from
+	for _, r := range reported {
+		if r["source_endpoint"] != zacks.SymbolEarningsURL(r["symbol"].(string)) {
+			t.Fatalf("unexpected %v endpoint: %v", r["symbol"], r["source_endpoint"])
+		}
+	}
+
+	upcoming := byDate[day("2024-04-25")]
+	if len(upcoming) != 1 || upcoming[0]["reported"] != "--" || upcoming[0]["estimate"] != "1.50" {
+		t.Fatalf("unexpected upcoming rows: %+v", upcoming)
+	}
+
+	if n := len(server.RequestsTo("/stock/research/ZZZZ/earnings-calendar")); n != 1 {
+		t.Fatalf("expected 1 ZZZZ request, got %v", n)
+	}
+}
+
+func TestParseJobParameters(t *testing.T) {
+	params, err := parseJobParameters([]map[string]interface{}{
+		{"symbol": "msft"},
+		{"symbols": []interface{}{"AAPL", " msft "}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(params.symbols) != 2 || params.symbols[0] != "MSFT" || params.symbols[1] != "AAPL" {
+		t.Fatalf("unexpected symbols: %v", params.symbols)
+	}
+
+	_, err = parseJobParameters([]map[string]interface{}{})
+	if err == nil {
+		t.Fatal("expected an error without symbols")
+	}
+}
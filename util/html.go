@@ -0,0 +1,118 @@
+package util
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Parses an HTML fragment, such as a table cell from a Zacks response.
+// Malformed markup is repaired the way a browser would, and entities are
+// decoded
+func ParseFragment(fragment string) []*html.Node {
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), context)
+	if err != nil {
+		return nil
+	}
+	return nodes
+}
+
+// Returns the first element, in document order, for which match returns
+// true, or nil
+func FindElement(nodes []*html.Node, match func(n *html.Node) bool) *html.Node {
+	for _, n := range nodes {
+		if n.Type == html.ElementNode && match(n) {
+			return n
+		}
+
+		children := []*html.Node{}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			children = append(children, c)
+		}
+		if found := FindElement(children, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Returns the value of an element's attribute
+func Attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// Reports whether an element's class list contains class
+func HasClass(n *html.Node, class string) bool {
+	value, _ := Attr(n, "class")
+	for _, c := range strings.Fields(value) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the visible text of the nodes with whitespace collapsed. Text
+// meant for screen readers only is left out
+func Text(nodes ...*html.Node) string {
+	b := &strings.Builder{}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+			b.WriteString(" ")
+		case n.Type == html.ElementNode && HasClass(n, "sr-only"):
+		default:
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c)
+			}
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// Returns the text of a table cell, e.g. "0.08" for
+// <div class="right pos positive pos_plus">0.08</div>. Plain text cells,
+// such as "--", are returned as they are
+func CellText(cell string) string {
+	return Text(ParseFragment(cell)...)
+}
+
+// Returns the text of the first element in a cell with the class, e.g.
+// "AAPL" for the hoverquote-symbol span of a symbol cell
+func CellClassText(cell, class string) string {
+	n := FindElement(ParseFragment(cell), func(n *html.Node) bool {
+		return HasClass(n, class)
+	})
+	if n == nil {
+		return ""
+	}
+	return Text(n)
+}
+
+// Returns the first value of an attribute in a cell, e.g. the company name
+// from <span title="Apple Inc." >
+func CellAttr(cell, key string) string {
+	n := FindElement(ParseFragment(cell), func(n *html.Node) bool {
+		_, ok := Attr(n, key)
+		return ok
+	})
+	if n == nil {
+		return ""
+	}
+	value, _ := Attr(n, key)
+	return value
+}
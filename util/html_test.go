@@ -0,0 +1,40 @@
+package util
+
+import "testing"
+
+func TestCellClassText(t *testing.T) {
+	cell := `<a href="/stock/quote/AAPL" rel="AAPL" class="hoverquote-container-od"><span class="hoverquote-symbol">AAPL<span class="sr-only"> Apple stock</span></span></a>`
+	if got := CellClassText(cell, "hoverquote-symbol"); got != "AAPL" {
+		t.Fatalf("unexpected symbol: %q", got)
+	}
+}
+
+func TestCellAttr(t *testing.T) {
+	cases := map[string]string{
+		`<span title="Apple Inc." >Apple Inc.</span>`:                     "Apple Inc.",
+		`<span class="co" title="AT&amp;T Inc.">AT&amp;T</span>`:          "AT&T Inc.",
+		`<span title='Lowe&#39;s Companies, "Inc."' >Lowe's</span>`:       `Lowe's Companies, "Inc."`,
+		`<span data-x="1"><span title="Nested Corp">Nested</span></span>`: "Nested Corp",
+	}
+	for cell, want := range cases {
+		if got := CellAttr(cell, "title"); got != want {
+			t.Fatalf("CellAttr(%q) = %q, want %q", cell, got, want)
+		}
+	}
+}
+
+func TestCellText(t *testing.T) {
+	cases := map[string]string{
+		`<div class="right pos positive pos_plus">0.08</div>`:     "0.08",
+		`<div class="right neg negative neg_minus" >-2.69%</div>`: "-2.69%",
+		`<span class="rank_chip rankrect_3">3</span>`:             "3",
+		`<a href="/stock/quote/T?q=T">AT&amp;T Inc.</a>`:          "AT&T Inc.",
+		"--": "--",
+		"":   "",
+	}
+	for cell, want := range cases {
+		if got := CellText(cell); got != want {
+			t.Fatalf("CellText(%q) = %q, want %q", cell, got, want)
+		}
+	}
+}
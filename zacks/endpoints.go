@@ -0,0 +1,93 @@
+package zacks
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/iamburbo/zacks-scraper/config"
+)
+
+// Environment variables overriding the base URLs
+const (
+	WWWEnv         = "ZACKS_WWW_URL"
+	ScreenerAPIEnv = "ZACKS_SCREENER_API_URL"
+)
+
+// Base URLs of the Zacks hosts. Set once at startup by Configure, e.g. to
+// point the scraper at a mock, a caching proxy or a recording proxy
+var (
+	WWW         = "https://www.zacks.com"
+	ScreenerAPI = "https://screener-api.zacks.com"
+)
+
+// Endpoint paths, relative to their host's base URL
+const (
+	loginPath          = "/"
+	stockScreenerPath  = "/screening/stock-screener"
+	calendarPath       = "/includes/classes/z2_class_calendarfunctions_data.php"
+	earningsExportPath = "/research/earnings/earning_export.php"
+	espPath            = "/esp/esp_buysell_data_handler.php"
+	symbolResearchPath = "/stock/research/"
+
+	screenerAPIPath    = "/"
+	resetParamPath     = "/reset_param.php"
+	runScreenPath      = "/getrunscreendata.php"
+	screenerExportPath = "/export.php"
+)
+
+func LoginURL() string          { return WWW + loginPath }
+func StockScreenerURL() string  { return WWW + stockScreenerPath }
+func CalendarURL() string       { return WWW + calendarPath }
+func EarningsExportURL() string { return WWW + earningsExportPath }
+func EspURL() string            { return WWW + espPath }
+
+// Earnings page of a symbol, listing its past and upcoming report dates
+func SymbolEarningsURL(symbol string) string {
+	return WWW + symbolResearchPath + symbol + "/earnings-calendar"
+}
+
+func ScreenerAPIURL() string    { return ScreenerAPI + screenerAPIPath }
+func ResetParamURL() string     { return ScreenerAPI + resetParamPath }
+func RunScreenURL() string      { return ScreenerAPI + runScreenPath }
+func ScreenerExportURL() string { return ScreenerAPI + screenerExportPath }
+
+// Sets the base URLs from the config, then from the environment, which
+// takes precedence. Unset values keep the Zacks defaults
+func Configure(urls config.BaseURLs) error {
+	www, err := baseURL(urls.WWW, "baseUrls.www", WWWEnv, WWW)
+	if err != nil {
+		return fmt.Errorf("invalid www base url: %w", err)
+	}
+
+	screenerAPI, err := baseURL(urls.ScreenerAPI, "baseUrls.screenerApi", ScreenerAPIEnv, ScreenerAPI)
+	if err != nil {
+		return fmt.Errorf("invalid screener api base url: %w", err)
+	}
+
+	WWW, ScreenerAPI = www, screenerAPI
+	return nil
+}
+
+// Errors name where the invalid value was set, the config key or the
+// environment variable
+func baseURL(configured, key, envName, current string) (string, error) {
+	value, source := current, "default"
+	if configured != "" {
+		value, source = configured, key
+	}
+	if env := os.Getenv(envName); env != "" {
+		value, source = env, envName
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("%v: %w", source, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("%v: %v must include a scheme and host", source, value)
+	}
+
+	return strings.TrimSuffix(value, "/"), nil
+}
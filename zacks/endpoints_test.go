@@ -0,0 +1,49 @@
+package zacks
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/iamburbo/zacks-scraper/config"
+)
+
+func TestConfigure(t *testing.T) {
+	www, screenerAPI := WWW, ScreenerAPI
+	defer func() { WWW, ScreenerAPI = www, screenerAPI }()
+
+	t.Setenv(WWWEnv, "")
+	t.Setenv(ScreenerAPIEnv, "http://localhost:9001")
+
+	err := Configure(config.BaseURLs{
+		WWW:         "http://localhost:9000/",
+		ScreenerAPI: "http://localhost:9002",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := CalendarURL(); got != "http://localhost:9000/includes/classes/z2_class_calendarfunctions_data.php" {
+		t.Fatalf("unexpected calendar url: %v", got)
+	}
+
+	// The environment takes precedence over the config
+	if got := ScreenerExportURL(); got != "http://localhost:9001/export.php" {
+		t.Fatalf("unexpected screener export url: %v", got)
+	}
+}
+
+func TestConfigureInvalid(t *testing.T) {
+	t.Setenv(WWWEnv, "")
+	t.Setenv(ScreenerAPIEnv, "")
+
+	err := Configure(config.BaseURLs{WWW: "localhost:9000"})
+	if err == nil || !strings.Contains(err.Error(), "baseUrls.www") {
+		t.Fatalf("expected an error naming the config key, got %v", err)
+	}
+
+	t.Setenv(ScreenerAPIEnv, "screener-api")
+	err = Configure(config.BaseURLs{})
+	if err == nil || !strings.Contains(err.Error(), ScreenerAPIEnv) {
+		t.Fatalf("expected an error naming the environment variable, got %v", err)
+	}
+}
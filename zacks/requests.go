@@ -11,7 +11,7 @@ import (
 
 // Sends login request to set session cookie in cookie jar
 func LogIn(client *http.Client, config *config.Config) error {
-	loginUrl, err := url.Parse("https://www.zacks.com")
+	loginUrl, err := url.Parse(LoginURL())
 	if err != nil {
 		return err
 	}
@@ -0,0 +1 @@
+window.app_data = {"data": [["<a href=\"/stock/quote/AAPL\" rel=\"AAPL\" class=\"hoverquote-container-od\"><span class=\"hoverquote-symbol\">AAPL<span class=\"sr-only\"></span></span></a>", "<span title=\"Apple Inc.\" >Apple Inc.</span>", "2,955,390.00", "$0.24", "0.50%", "2/9/2024", "191.56", "2/15/2024"], ["<a href=\"/stock/quote/KO\" rel=\"KO\" class=\"hoverquote-container-od\"><span class=\"hoverquote-symbol\">KO<span class=\"sr-only\"></span></span></a>", "<span title=\"The Coca-Cola Company\" >The Coca-Cola Company</span>", "261,690.00", "$0.46", "3.07%", "2/14/2024", "60.54", "4/1/2024"]]}
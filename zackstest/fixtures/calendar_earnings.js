@@ -0,0 +1 @@
+window.app_data = {"data": [["<a href=\"/stock/quote/AAPL\" rel=\"AAPL\" class=\"hoverquote-container-od\"><span class=\"hoverquote-symbol\">AAPL<span class=\"sr-only\"></span></span></a>", "<span title=\"Apple Inc.\" >Apple Inc.</span>", "2,955,390.00", "After Close", "2.10", "2.18", "<div class=\"right pos positive pos_plus\">0.08</div>", "<div class=\"right pos positive pos_plus\">3.81%</div>", "<div class=\"right pos positive pos_plus\">1.20%</div>"], ["<a href=\"/stock/quote/MSFT\" rel=\"MSFT\" class=\"hoverquote-container-od\"><span class=\"hoverquote-symbol\">MSFT<span class=\"sr-only\"></span></span></a>", "<span title=\"Microsoft Corporation\" >Microsoft Corporation</span>", "2,987,580.00", "After Close", "2.78", "2.93", "<div class=\"right pos positive pos_plus\">0.15</div>", "<div class=\"right pos positive pos_plus\">5.40%</div>", "<div class=\"right neg negative neg_minus\">-2.69%</div>"], ["<a href=\"/stock/quote/XOM\" rel=\"XOM\" class=\"hoverquote-container-od\"><span class=\"hoverquote-symbol\">XOM<span class=\"sr-only\"></span></span></a>", "<span title=\"Exxon Mobil Corporation\" >Exxon Mobil Corporation</span>", "408,220.00", "Before Open", "2.20", "--", "--", "--", "<div class=\"right pos positive pos_plus\">0.31%</div>"]]}
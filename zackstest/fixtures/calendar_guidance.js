@@ -0,0 +1 @@
+window.app_data = {"data": [["<a href=\"/stock/quote/AAPL\" rel=\"AAPL\" class=\"hoverquote-container-od\"><span class=\"hoverquote-symbol\">AAPL<span class=\"sr-only\"></span></span></a>", "<span title=\"Apple Inc.\" >Apple Inc.</span>", "2,955,390.00", "Q", "3/2024", "0.00 - 0.00", "0.00", "1.49", "NA"], ["<a href=\"/stock/quote/NKE\" rel=\"NKE\" class=\"hoverquote-container-od\"><span class=\"hoverquote-symbol\">NKE<span class=\"sr-only\"></span></span></a>", "<span title=\"NIKE, Inc.\" >NIKE, Inc.</span>", "152,100.00", "FY", "5/2024", "3.10 - 3.35", "3.23", "3.43", "-2.33%"], ["<a href=\"/stock/quote/NKE\" rel=\"NKE\" class=\"hoverquote-container-od\"><span class=\"hoverquote-symbol\">NKE<span class=\"sr-only\"></span></span></a>", "<span title=\"NIKE, Inc.\" >NIKE, Inc.</span>", "152,100.00", "Q", "2/2024", "0.95 - 1.05", "1.00", "1.02", "-2.94%"]]}
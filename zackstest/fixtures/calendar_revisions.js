@@ -0,0 +1 @@
+window.app_data = {"data": [["<a href=\"/stock/quote/AAPL\" rel=\"AAPL\" class=\"hoverquote-container-od\"><span class=\"hoverquote-symbol\">AAPL<span class=\"sr-only\"></span></span></a>", "<span title=\"Apple Inc.\" >Apple Inc.</span>", "2,955,390.00", "Q", "3/2024", "1.55", "1.60", "<div class=\"right pos positive pos_plus\">3.23%</div>", "1.57", "<div class=\"right pos positive pos_plus\">1.91%</div>"], ["<a href=\"/stock/quote/INTC\" rel=\"INTC\" class=\"hoverquote-container-od\"><span class=\"hoverquote-symbol\">INTC<span class=\"sr-only\"></span></span></a>", "<span title=\"Intel Corporation\" >Intel Corporation</span>", "183,330.00", "FY", "12/2024", "1.50", "1.40", "<div class=\"right neg negative neg_minus\">-6.67%</div>", "1.44", "NA"]]}
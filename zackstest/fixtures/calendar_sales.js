@@ -0,0 +1 @@
+window.app_data = {"data": [["<a href=\"/stock/quote/AAPL\" rel=\"AAPL\" class=\"hoverquote-container-od\"><span class=\"hoverquote-symbol\">AAPL<span class=\"sr-only\"></span></span></a>", "<span title=\"Apple Inc.\" >Apple Inc.</span>", "2,955,390.00", "After Close", "117,910.00", "119,580.00", "<div class=\"right pos positive pos_plus\">1,670.00</div>", "<div class=\"right pos positive pos_plus\">1.42%</div>", "<div class=\"right pos positive pos_plus\">1.20%</div>"], ["<a href=\"/stock/quote/MSFT\" rel=\"MSFT\" class=\"hoverquote-container-od\"><span class=\"hoverquote-symbol\">MSFT<span class=\"sr-only\"></span></span></a>", "<span title=\"Microsoft Corporation\" >Microsoft Corporation</span>", "2,987,580.00", "After Close", "61,120.00", "62,020.00", "<div class=\"right pos positive pos_plus\">900.00</div>", "<div class=\"right pos positive pos_plus\">1.47%</div>", "<div class=\"right neg negative neg_minus\">-2.69%</div>"]]}
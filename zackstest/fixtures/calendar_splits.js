@@ -0,0 +1 @@
+window.app_data = {"data": [["<a href=\"/stock/quote/NVDA\" rel=\"NVDA\" class=\"hoverquote-container-od\"><span class=\"hoverquote-symbol\">NVDA<span class=\"sr-only\"></span></span></a>", "<span title=\"NVIDIA Corporation\" >NVIDIA Corporation</span>", "2,955,390.00", "1,208.88", "10-1"]]}
@@ -0,0 +1 @@
+window.app_data = {"data": [["<a href=\"/stock/quote/AAPL\" rel=\"AAPL\" class=\"hoverquote-container-od\"><span class=\"hoverquote-symbol\">AAPL<span class=\"sr-only\"></span></span></a>", "<span title=\"Apple Inc.\" >Apple Inc.</span>", "2,955,390.00", "Q1 2024 Earnings Call", "<a href=\"/stock/research/AAPL/earnings-call-transcripts\">Transcript</a>"], ["<a href=\"/stock/quote/NKE\" rel=\"NKE\" class=\"hoverquote-container-od\"><span class=\"hoverquote-symbol\">NKE<span class=\"sr-only\"></span></span></a>", "<span title=\"NIKE, Inc.\" >NIKE, Inc.</span>", "152,100.00", "Q2 2024 Earnings Call", ""]]}
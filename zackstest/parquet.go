@@ -0,0 +1,56 @@
+package zackstest
+
+import (
+	"reflect"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+// Reads a parquet file written by the scraper into rows keyed by column
+// name. Optional values are dereferenced, nulls are nil
+func ReadParquet(path string) ([]map[string]interface{}, error) {
+	f, err := local.NewLocalFileReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pr, err := reader.NewParquetReader(f, nil, 1)
+	if err != nil {
+		return nil, err
+	}
+	defer pr.ReadStop()
+
+	columnNames := map[string]string{}
+	for _, info := range pr.SchemaHandler.Infos[1:] {
+		columnNames[info.InName] = info.ExName
+	}
+
+	res, err := pr.ReadByNumber(int(pr.GetNumRows()))
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []map[string]interface{}{}
+	for _, r := range res {
+		v := reflect.ValueOf(r)
+		row := map[string]interface{}{}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			name := columnNames[v.Type().Field(i).Name]
+
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					row[name] = nil
+					continue
+				}
+				field = field.Elem()
+			}
+			row[name] = field.Interface()
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,316 @@
+// Package zackstest provides a fake Zacks for running the scrapers offline.
+// It serves the responses in fixtures/ for login, the stock screener flow,
+// the ESP filter, the earnings calendar and its transcripts, the earnings
+// pages of symbols and the earnings export. The fixtures are written by
+// hand in the formats the parsers read, not captured from Zacks, so they
+// can't confirm anything the parsers don't already assume
+package zackstest
+
+import (
+	"embed"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/iamburbo/zacks-scraper/config"
+	"golang.org/x/net/publicsuffix"
+)
+
+//go:embed fixtures
+var fixtures embed.FS
+
+const (
+	Username = "test@example.com"
+	Password = "hunter2"
+
+	sessionCookie = "CURRENT_USER"
+	sessionValue  = "zackstest-session"
+	screenerCKey  = "0675466c5b74cfac34f6be7dc37d4fe6a008e212e2ef73bdcd7e9f1f9a9bd377"
+)
+
+// Calendar tab ids used by z2_class_calendarfunctions_data.php
+var calendarTabs = map[string]string{
+	"1": "earnings",
+	"3": "revisions",
+	"4": "splits",
+	"5": "dividends",
+	"6": "guidance",
+	"8": "transcripts",
+	"9": "sales",
+}
+
+// A request received by the fake
+type Request struct {
+	Method string
+	Host   string
+	Path   string
+	Query  url.Values
+	Form   url.Values // url-encoded or multipart form fields
+}
+
+type Server struct {
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	requests  []Request
+	overrides map[string][]byte
+	screenTab string // tab_id of the last screen run, picks the export
+}
+
+// Starts a fake Zacks. Close it when done
+func NewServer() *Server {
+	s := &Server{}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// A client whose requests to any Zacks host are answered by the fake. The
+// original host is kept, so cookies behave as they do against Zacks
+func (s *Server) Client() *http.Client {
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		panic(err)
+	}
+
+	target, err := url.Parse(s.srv.URL)
+	if err != nil {
+		panic(err)
+	}
+
+	return &http.Client{
+		Jar: jar,
+		Transport: &rewriteTransport{
+			target: target,
+			next:   s.srv.Client().Transport,
+		},
+	}
+}
+
+// Config with the credentials the fake accepts
+func (s *Server) Config() *config.Config {
+	return &config.Config{
+		Username:   Username,
+		Password:   Password,
+		MaxRetries: 1,
+	}
+}
+
+// Every request received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request{}, s.requests...)
+}
+
+// Requests received for a path
+func (s *Server) RequestsTo(path string) []Request {
+	requests := []Request{}
+	for _, r := range s.Requests() {
+		if r.Path == path {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+// Serves body in place of a fixture, e.g. to simulate a change at Zacks
+func (s *Server) SetFixture(fixture string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.overrides == nil {
+		s.overrides = map[string][]byte{}
+	}
+	s.overrides[fixture] = body
+}
+
+type rewriteTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(req.URL.Hostname(), "zacks.com") {
+		return nil, fmt.Errorf("zackstest: unexpected host %v", req.URL.Host)
+	}
+
+	r := req.Clone(req.Context())
+	r.Host = req.URL.Host
+	r.URL.Scheme = t.target.Scheme
+	r.URL.Host = t.target.Host
+	return t.next.RoundTrip(r)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.record(r)
+
+	host := r.Host
+	switch {
+	case host == "www.zacks.com" && r.URL.Path == "/" && r.Method == "POST":
+		s.login(w, r)
+	case host == "www.zacks.com" && r.URL.Path == "/screening/stock-screener":
+		s.authorized(w, r, "text/html; charset=UTF-8", "stock_screener.html")
+	case host == "www.zacks.com" && r.URL.Path == "/esp/esp_buysell_data_handler.php":
+		s.esp(w, r)
+	case host == "www.zacks.com" && r.URL.Path == "/includes/classes/z2_class_calendarfunctions_data.php":
+		s.calendar(w, r)
+	case host == "www.zacks.com" && strings.HasPrefix(r.URL.Path, "/stock/research/") && strings.HasSuffix(r.URL.Path, "/earnings-call-transcripts"):
+		s.authorized(w, r, "text/html; charset=UTF-8", "transcript.html")
+	case host == "www.zacks.com" && strings.HasPrefix(r.URL.Path, "/stock/research/") && strings.HasSuffix(r.URL.Path, "/earnings-calendar"):
+		s.symbolEarnings(w, r)
+	case host == "www.zacks.com" && r.URL.Path == "/research/earnings/earning_export.php":
+		s.authorized(w, r, "application/vnd.ms-excel", "earnings_export_"+r.URL.Query().Get("tab_id")+".tsv")
+	case host == "screener-api.zacks.com" && r.URL.Path == "/":
+		if r.URL.Query().Get("c_key") != screenerCKey {
+			http.Error(w, "invalid c_key", http.StatusForbidden)
+			return
+		}
+		s.authorized(w, r, "text/html; charset=UTF-8", "screener_api.html")
+	case host == "screener-api.zacks.com" && r.URL.Path == "/reset_param.php":
+		s.authorized(w, r, "text/html; charset=UTF-8", "")
+	case host == "screener-api.zacks.com" && r.URL.Path == "/getrunscreendata.php":
+		s.runScreen(w, r)
+	case host == "screener-api.zacks.com" && r.URL.Path == "/export.php":
+		s.export(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) record(r *http.Request) {
+	req := Request{
+		Method: r.Method,
+		Host:   r.Host,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Form:   url.Values{},
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("content-type"))
+	switch mediaType {
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(1 << 20); err == nil {
+			req.Form = r.MultipartForm.Value
+		}
+	case "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err == nil {
+			req.Form = r.PostForm
+		}
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("force_login") != "true" || q.Get("username") != Username || q.Get("password") != Password {
+		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:   sessionCookie,
+		Value:  sessionValue,
+		Domain: "zacks.com",
+		Path:   "/",
+	})
+	w.Header().Set("content-type", "text/html; charset=UTF-8")
+	w.Write([]byte("<html><body>Welcome</body></html>"))
+}
+
+func (s *Server) esp(w http.ResponseWriter, r *http.Request) {
+	switch r.PostForm.Get("hd_esp_type") {
+	case "1":
+		s.authorized(w, r, "application/json", "esp_buys.json")
+	case "2":
+		s.authorized(w, r, "application/json", "esp_sells.json")
+	default:
+		http.Error(w, "unknown esp type", http.StatusBadRequest)
+	}
+}
+
+func (s *Server) runScreen(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if r.MultipartForm != nil {
+		s.screenTab = strings.Join(r.MultipartForm.Value["tab_id"], "")
+	}
+	s.mu.Unlock()
+
+	s.authorized(w, r, "text/html; charset=UTF-8", "")
+}
+
+// Exports the view the last screen ran with. Views without their own
+// fixture get the default one
+func (s *Server) export(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	fixture := "screener_export_" + s.screenTab + ".csv"
+	_, ok := s.overrides[fixture]
+	s.mu.Unlock()
+
+	if _, err := fixtures.ReadFile("fixtures/" + fixture); err != nil && !ok {
+		fixture = "screener_export.csv"
+	}
+	s.authorized(w, r, "text/csv", fixture)
+}
+
+// Serves the earnings page of symbols with a fixture, and 404 for others
+func (s *Server) symbolEarnings(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/stock/research/"), "/earnings-calendar")
+	fixture := "symbol_earnings_" + symbol + ".html"
+
+	s.mu.Lock()
+	_, ok := s.overrides[fixture]
+	s.mu.Unlock()
+
+	if _, err := fixtures.ReadFile("fixtures/" + fixture); err != nil && !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.authorized(w, r, "text/html; charset=UTF-8", fixture)
+}
+
+func (s *Server) calendar(w http.ResponseWriter, r *http.Request) {
+	tab, ok := calendarTabs[r.URL.Query().Get("type")]
+	if !ok || r.URL.Query().Get("calltype") != "eventscal" {
+		http.Error(w, "unknown calendar type", http.StatusBadRequest)
+		return
+	}
+
+	s.authorized(w, r, "text/html; charset=UTF-8", "calendar_"+tab+".js")
+}
+
+// Serves a fixture to logged in clients. An empty fixture sends an empty body
+func (s *Server) authorized(w http.ResponseWriter, r *http.Request, contentType, fixture string) {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil || c.Value != sessionValue {
+		http.Error(w, "not logged in", http.StatusForbidden)
+		return
+	}
+
+	s.mu.Lock()
+	body, ok := s.overrides[fixture]
+	s.mu.Unlock()
+
+	if !ok && fixture != "" {
+		body, err = fixtures.ReadFile("fixtures/" + fixture)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	w.Header().Set("content-type", contentType)
+	w.Write(body)
+}